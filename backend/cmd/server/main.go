@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
-	// "fmt"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -28,9 +29,33 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Handle subcommands before starting the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrateCommand(cfg, os.Args[2:])
+			return
+		case "leaderboards":
+			runLeaderboardsCommand(cfg, os.Args[2:])
+			return
+		case "achievements":
+			runAchievementsCommand(cfg, os.Args[2:])
+			return
+		case "xp":
+			runXPCommand(cfg, os.Args[2:])
+			return
+		}
+	}
+
 	// Set Gin mode
 	gin.SetMode(cfg.GinMode)
 
+	// Resolve the time zone leaderboard periods roll over in
+	leaderboardLocation, err := time.LoadLocation(cfg.LeaderboardTimezone)
+	if err != nil {
+		log.Fatalf("Invalid LEADERBOARD_TIMEZONE: %v", err)
+	}
+
 	// Initialize database
 	db, err := database.NewPostgresConnection(cfg.DatabaseURL)
 	if err != nil {
@@ -52,12 +77,28 @@ func main() {
 
 	// Initialize services
 	sessionService := services.NewSessionService(db, redisClient)
+	playerService := services.NewPlayerService(db, redisClient, sessionService)
+	profileService := services.NewProfileService(db, redisClient)
 	gameService := services.NewGameService(db, redisClient)
-	scoreService := services.NewScoreService(db, redisClient)
-	leaderboardService := services.NewLeaderboardService(db, redisClient)
+	achievementService := services.NewAchievementService(db)
+	scoreService := services.NewScoreService(db, redisClient, leaderboardLocation, achievementService)
+	leaderboardService := services.NewLeaderboardService(db, redisClient, leaderboardLocation)
+	saveService := services.NewSaveService(db)
+	statsService := services.NewStatsService(db, redisClient, leaderboardLocation)
+
+	// Archive closed leaderboard periods in the background
+	archiverCtx, stopArchiver := context.WithCancel(context.Background())
+	defer stopArchiver()
+	go leaderboardService.RunArchiver(archiverCtx, 10*time.Minute)
+
+	// Unlock newly added achievements for players whose history already meets them
+	go achievementService.BackfillNew(archiverCtx)
+
+	// Credit XP for scores accepted before XP was introduced
+	go services.NewXPService(db).BackfillUncredited(archiverCtx)
 
 	// Initialize handlers
-	h := handlers.New(sessionService, gameService, scoreService, leaderboardService)
+	h := handlers.New(sessionService, playerService, profileService, gameService, scoreService, leaderboardService, saveService, statsService, achievementService)
 
 	// Setup router
 	router := setupRouter(h, db, redisClient, cfg)
@@ -99,6 +140,12 @@ func main() {
 func setupRouter(h *handlers.Handlers, db *pgxpool.Pool, redisClient *redis.Client, cfg *config.Config) *gin.Engine {
 	router := gin.New()
 
+	// Only believe X-Forwarded-For from our own proxies, so clients cannot
+	// choose the IP address rate limits are keyed on
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Add middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORS())
@@ -113,25 +160,263 @@ func setupRouter(h *handlers.Handlers, db *pgxpool.Pool, redisClient *redis.Clie
 	{
 		// Session management
 		api.POST("/users/session", h.CreateSession)
+		sessions := api.Group("/sessions")
+		sessions.Use(middleware.SessionAuth())
+		{
+			sessions.GET("/me", h.GetCurrentSession)
+			sessions.DELETE("/me", h.RevokeSession)
+			sessions.POST("/refresh", h.RefreshSession)
+		}
+
+		// Player accounts and pairing codes, which let a player use several devices
+		api.POST("/players/login", h.Login)
+		api.POST("/players/pair", h.RedeemPairingCode)
+		players := api.Group("/players")
+		players.Use(middleware.SessionAuth())
+		{
+			players.GET("/me", h.GetCurrentPlayer)
+			players.POST("/register", h.RegisterPlayer)
+			players.POST("/pairing-codes", h.CreatePairingCode)
+			players.GET("/me/profile", h.GetMyProfile)
+			players.PUT("/me/profile", h.UpdateMyProfile)
+			players.GET("/me/stats", h.GetMyStats)
+			players.GET("/me/recent-runs", h.GetRecentRuns)
+			players.GET("/me/achievements", h.GetMyAchievements)
+		}
+		api.GET("/profiles/avatars", h.GetAvatars)
+
+		// Achievement catalogue
+		api.GET("/achievements", h.GetAchievements)
+		api.GET("/achievements/:achievementId", h.GetAchievement)
 
 		// Game management
 		api.GET("/games", h.GetGames)
+		api.GET("/categories", h.GetCategories)
+
+		// Run tickets, issued when a game starts and redeemed by its score
+		api.POST("/runs", middleware.SessionAuth(), h.StartRun)
 
 		// Score management
 		scores := api.Group("/scores")
 		scores.Use(middleware.SessionAuth())
 		{
 			scores.POST("", h.SubmitScore)
+			scores.POST("/batch", h.SubmitScoreBatch)
+			scores.POST("/import", h.ImportScores)
 			scores.GET("/:gameId", h.GetUserScores)
 		}
 
+		// Cloud saves of in-progress games, in named slots per game
+		saves := api.Group("/saves")
+		saves.Use(middleware.SessionAuth())
+		{
+			saves.GET("/:gameId", h.ListSaves)
+			saves.GET("/:gameId/:slot", h.GetSave)
+			saves.PUT("/:gameId/:slot", h.PutSave)
+			saves.DELETE("/:gameId/:slot", h.DeleteSave)
+			saves.GET("/:gameId/:slot/revisions", h.ListSaveRevisions)
+			saves.GET("/:gameId/:slot/revisions/:version", h.GetSaveRevision)
+			saves.POST("/:gameId/:slot/revisions/:version/restore", h.RestoreSave)
+		}
+
+		// Replays of verified puzzle scores
+		api.GET("/replays/:scoreId", h.GetReplay)
+
 		// Leaderboard endpoints
 		leaderboards := api.Group("/leaderboards")
 		{
 			leaderboards.GET("/:gameId", h.GetGameLeaderboard)
+			leaderboards.GET("/:gameId/around-me", middleware.SessionAuth(), h.GetLeaderboardAroundMe)
+			leaderboards.GET("/:gameId/archive", h.GetLeaderboardArchive)
+			leaderboards.GET("/:gameId/archive/:period/:periodKey", h.GetArchivedStandings)
 			leaderboards.GET("/global", h.GetGlobalLeaderboard)
+			leaderboards.GET("/xp", h.GetXPLeaderboard)
+			leaderboards.GET("/category/:category", h.GetCategoryLeaderboard)
+		}
+
+		// Moderation, guarded by ADMIN_TOKEN
+		admin := api.Group("/admin")
+		admin.Use(middleware.AdminAuth(cfg.AdminToken))
+		{
+			admin.POST("/profiles/:displayName/reset", h.ResetDisplayName)
 		}
 	}
 
 	return router
-}
\ No newline at end of file
+}
+
+const migrateUsage = "usage: server migrate up|down [steps]|status|to <version>"
+
+// runMigrateCommand handles the `migrate` subcommand and exits
+func runMigrateCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	db, err := database.NewPostgresConnection(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator := database.NewMigrator(db)
+
+	switch args[0] {
+	case "up":
+		version, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Database is at version %d", version)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		version, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		log.Printf("Database is at version %d", version)
+
+	case "to":
+		if len(args) < 2 {
+			log.Fatal(migrateUsage)
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal(migrateUsage)
+		}
+		version, err := migrator.To(ctx, target)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Database is at version %d", version)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		inProgress, err := migrator.InProgress(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		if inProgress {
+			fmt.Fprintln(os.Stdout, "migration in progress; applied versions may still change")
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.ChecksumMismatch {
+				state += " (checksum mismatch)"
+			}
+			fmt.Fprintf(os.Stdout, "%4d  %-32s %s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+const leaderboardsUsage = "usage: server leaderboards rebuild [gameId ...]"
+
+// runLeaderboardsCommand handles the `leaderboards` subcommand and exits
+func runLeaderboardsCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "rebuild" {
+		log.Fatal(leaderboardsUsage)
+	}
+
+	db, err := database.NewPostgresConnection(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	redisClient, err := database.NewRedisConnection(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisClient.Close()
+
+	leaderboardLocation, err := time.LoadLocation(cfg.LeaderboardTimezone)
+	if err != nil {
+		log.Fatalf("Invalid LEADERBOARD_TIMEZONE: %v", err)
+	}
+
+	ctx := context.Background()
+	leaderboardService := services.NewLeaderboardService(db, redisClient, leaderboardLocation)
+
+	// Rebuild only the named games, or every game if none were given
+	if gameIDs := args[1:]; len(gameIDs) > 0 {
+		for _, gameID := range gameIDs {
+			count, err := leaderboardService.RebuildGameLeaderboard(ctx, gameID)
+			if err != nil {
+				log.Fatalf("Failed to rebuild %s: %v", gameID, err)
+			}
+			log.Printf("Rebuilt %s: %d scores", gameID, count)
+		}
+		return
+	}
+
+	counts, err := leaderboardService.RebuildAllLeaderboards(ctx)
+	if err != nil {
+		log.Fatalf("Failed to rebuild leaderboards: %v", err)
+	}
+	for gameID, count := range counts {
+		log.Printf("Rebuilt %s: %d scores", gameID, count)
+	}
+}
+
+const achievementsUsage = "usage: server achievements backfill [achievementId ...]"
+
+// runAchievementsCommand handles the `achievements` subcommand and exits
+func runAchievementsCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "backfill" {
+		log.Fatal(achievementsUsage)
+	}
+
+	db, err := database.NewPostgresConnection(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Backfill only the named achievements, or those never backfilled if none were given
+	counts, err := services.NewAchievementService(db).Backfill(context.Background(), args[1:])
+	if err != nil {
+		log.Fatalf("Failed to backfill achievements: %v", err)
+	}
+	for achievementID, count := range counts {
+		log.Printf("Backfilled %s: %d players", achievementID, count)
+	}
+}
+
+const xpUsage = "usage: server xp backfill"
+
+// runXPCommand handles the `xp` subcommand and exits
+func runXPCommand(cfg *config.Config, args []string) {
+	if len(args) != 1 || args[0] != "backfill" {
+		log.Fatal(xpUsage)
+	}
+
+	db, err := database.NewPostgresConnection(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Credit every accepted score that has no XP yet
+	count, err := services.NewXPService(db).Backfill(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to backfill XP: %v", err)
+	}
+	log.Printf("Backfilled XP for %d scores", count)
+}
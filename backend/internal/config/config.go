@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,18 @@ type Config struct {
 	RedisURL    string
 	GinMode     string
 	RateLimit   int
+
+	// LeaderboardTimezone is the IANA zone daily, weekly and monthly
+	// leaderboard periods roll over in
+	LeaderboardTimezone string
+
+	// AdminToken guards the admin endpoints; they are disabled when empty
+	AdminToken string
+
+	// TrustedProxies are the addresses or CIDR ranges whose X-Forwarded-For
+	// header is believed when working out a client's IP address. With none,
+	// the address of the connection is used.
+	TrustedProxies []string
 }
 
 // Load reads configuration from environment variables and .env file
@@ -27,6 +40,12 @@ func Load() (*Config, error) {
 		RedisURL:    getEnv("REDIS_URL", ""),
 		GinMode:     getEnv("GIN_MODE", "release"),
 		RateLimit:   getEnvAsInt("RATE_LIMIT", 100),
+
+		LeaderboardTimezone: getEnv("LEADERBOARD_TIMEZONE", "UTC"),
+
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+
+		TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 	}
 
 	return cfg, nil
@@ -48,4 +67,16 @@ func getEnvAsInt(key string, fallback int) int {
 		}
 	}
 	return fallback
+}
+
+// getEnvAsList gets a comma-separated environment variable as a list,
+// skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
 }
\ No newline at end of file
@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID is the pg_advisory_lock key held while migrating so that
+// replicas booting at the same time apply migrations one after another
+const migrationLockID int64 = 7_301_999_420_001
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version          int
+	Name             string
+	Applied          bool
+	AppliedAt        time.Time
+	ChecksumMismatch bool
+}
+
+// Migrator applies and reverts versioned schema migrations
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the registered migrations
+func NewMigrator(db *pgxpool.Pool) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+	}
+}
+
+// LatestVersion returns the highest registered migration version
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations and returns the resulting version
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.LatestVersion())
+}
+
+// Down reverts the given number of most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	var version int
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.appliedChecksums(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verifyChecksums(applied); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			steps--
+		}
+
+		version, err = currentVersion(ctx, conn)
+		return err
+	})
+	return version, err
+}
+
+// To migrates up or down until the database is at the target version
+func (m *Migrator) To(ctx context.Context, target int) (int, error) {
+	if target < 0 || target > m.LatestVersion() {
+		return 0, fmt.Errorf("unknown migration version %d (latest is %d)", target, m.LatestVersion())
+	}
+
+	var version int
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.appliedChecksums(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verifyChecksums(applied); err != nil {
+			return err
+		}
+
+		// Revert anything above the target, newest first
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > target {
+				if err := m.revert(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Apply anything pending up to the target, oldest first
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+				if err := m.apply(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+
+		version, err = currentVersion(ctx, conn)
+		return err
+	})
+	return version, err
+}
+
+// Status reports every registered migration and whether it has been applied.
+// It reads the ledger without taking the migration lock, so it answers
+// while another replica is migrating; InProgress reports whether one is.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	// Nothing has been applied until the first migration creates the ledger
+	var exists bool
+	if err := m.db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	type record struct {
+		checksum  string
+		appliedAt time.Time
+	}
+	applied := make(map[int]record)
+	if exists {
+		rows, err := m.db.Query(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var version int
+			var r record
+			if err := rows.Scan(&version, &r.checksum, &r.appliedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+			}
+			applied[version] = r
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if r, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = r.appliedAt
+			status.ChecksumMismatch = r.checksum != checksum(migration)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// InProgress reports whether another connection holds the migration lock,
+// without waiting for it
+func (m *Migrator) InProgress(ctx context.Context) (bool, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, migrationLockID).Scan(&acquired); err != nil {
+		return false, fmt.Errorf("failed to check migration lock: %w", err)
+	}
+	if acquired {
+		conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}
+	return !acquired, nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.Exec(ctx, createSchemaMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedChecksums returns the recorded checksum of every applied version
+func (m *Migrator) appliedChecksums(ctx context.Context, conn *pgxpool.Conn) (map[int]string, error) {
+	rows, err := conn.Query(ctx, `SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var sum string
+		if err := rows.Scan(&version, &sum); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = sum
+	}
+
+	return applied, rows.Err()
+}
+
+// verifyChecksums refuses to continue if an applied migration was edited
+func (m *Migrator) verifyChecksums(applied map[int]string) error {
+	for _, migration := range m.migrations {
+		if sum, ok := applied[migration.Version]; ok && sum != checksum(migration) {
+			return fmt.Errorf("migration %d (%s) has changed since it was applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// apply runs a migration's up script and records it in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, checksum(migration),
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// revert runs a migration's down script and removes its record in one transaction
+func (m *Migrator) revert(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d (%s) is not reversible", migration.Version, migration.Name)
+	}
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// currentVersion returns the highest applied migration version
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (int, error) {
+	var version int
+	err := conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// checksum fingerprints a migration's up script
+func checksum(migration Migration) string {
+	sum := sha256.Sum256([]byte(migration.Up))
+	return hex.EncodeToString(sum[:])
+}
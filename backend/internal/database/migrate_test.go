@@ -0,0 +1,38 @@
+package database
+
+import "testing"
+
+func TestVerifyChecksums(t *testing.T) {
+	first := Migration{Version: 1, Name: "create_players", Up: "CREATE TABLE players ();"}
+	second := Migration{Version: 2, Name: "create_scores", Up: "CREATE TABLE scores ();"}
+	m := &Migrator{migrations: []Migration{first, second}}
+
+	tests := []struct {
+		name    string
+		applied map[int]string
+		wantErr bool
+	}{
+		{"nothing applied", map[int]string{}, false},
+		{"some applied", map[int]string{1: checksum(first)}, false},
+		{"all applied", map[int]string{1: checksum(first), 2: checksum(second)}, false},
+		{"edited after applying", map[int]string{1: checksum(first), 2: checksum(first)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.verifyChecksums(tt.applied); (err != nil) != tt.wantErr {
+				t.Errorf("verifyChecksums() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationVersionsAreUnique(t *testing.T) {
+	seen := make(map[int]string)
+	for _, migration := range migrations {
+		if name, ok := seen[migration.Version]; ok {
+			t.Errorf("version %d is used by both %s and %s", migration.Version, name, migration.Name)
+		}
+		seen[migration.Version] = migration.Name
+	}
+}
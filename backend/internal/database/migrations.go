@@ -2,37 +2,54 @@ package database
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// RunMigrations executes all database migrations
-func RunMigrations(db *pgxpool.Pool) error {
-	migrations := []string{
-		createSessionsTable,
-		createGamesTable,
-		createScoresTable,
-		createIndexes,
-		insertInitialGames,
-	}
-
-	for i, migration := range migrations {
-		if err := executeMigration(db, migration, i+1); err != nil {
-			return fmt.Errorf("migration %d failed: %w", i+1, err)
-		}
-	}
-
-	return nil
+// Migration is a single numbered, reversible schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
 }
 
-// executeMigration runs a single migration
-func executeMigration(db *pgxpool.Pool, migration string, version int) error {
-	_, err := db.Exec(context.Background(), migration)
-	if err != nil {
-		return fmt.Errorf("failed to execute migration %d: %w", version, err)
-	}
-	return nil
+// migrations lists every schema change in the order it must be applied.
+// Append new entries with the next version number; never edit or reorder
+// a migration that has already shipped, since its checksum is recorded.
+var migrations = []Migration{
+	{Version: 1, Name: "create_sessions", Up: createSessionsTable, Down: dropSessionsTable},
+	{Version: 2, Name: "create_games", Up: createGamesTable, Down: dropGamesTable},
+	{Version: 3, Name: "create_scores", Up: createScoresTable, Down: dropScoresTable},
+	{Version: 4, Name: "create_indexes", Up: createIndexes, Down: dropIndexes},
+	{Version: 5, Name: "seed_games", Up: insertInitialGames, Down: deleteInitialGames},
+	{Version: 6, Name: "create_leaderboard_archives", Up: createLeaderboardArchives, Down: dropLeaderboardArchives},
+	{Version: 7, Name: "add_game_scoring", Up: addGameScoring, Down: dropGameScoring},
+	{Version: 8, Name: "create_run_tickets", Up: createRunTickets, Down: dropRunTickets},
+	{Version: 9, Name: "add_score_plausibility", Up: addScorePlausibility, Down: dropScorePlausibility},
+	{Version: 10, Name: "create_score_replays", Up: createScoreReplays, Down: dropScoreReplays},
+	{Version: 11, Name: "add_score_metadata", Up: addScoreMetadata, Down: dropScoreMetadata},
+	{Version: 12, Name: "create_submission_keys", Up: createSubmissionKeys, Down: dropSubmissionKeys},
+	{Version: 13, Name: "add_imported_scores", Up: addImportedScores, Down: dropImportedScores},
+	{Version: 14, Name: "create_game_saves", Up: createGameSaves, Down: dropGameSaves},
+	{Version: 15, Name: "create_game_save_revisions", Up: createGameSaveRevisions, Down: dropGameSaveRevisions},
+	{Version: 16, Name: "add_session_revocation", Up: addSessionRevocation, Down: dropSessionRevocation},
+	{Version: 17, Name: "create_players", Up: createPlayers, Down: dropPlayers},
+	{Version: 18, Name: "key_saves_on_players", Up: keySavesOnPlayers, Down: keySavesOnSessions},
+	{Version: 19, Name: "create_player_profiles", Up: createPlayerProfiles, Down: dropPlayerProfiles},
+	{Version: 20, Name: "create_player_stats", Up: createPlayerStats, Down: dropPlayerStats},
+	{Version: 21, Name: "index_player_history", Up: indexPlayerHistory, Down: dropPlayerHistoryIndex},
+	{Version: 22, Name: "create_achievements", Up: createAchievements, Down: dropAchievements},
+	{Version: 23, Name: "create_xp_ledger", Up: createXPLedger, Down: dropXPLedger},
+	{Version: 24, Name: "create_game_save_versions", Up: createGameSaveVersions, Down: dropGameSaveVersions},
+	{Version: 25, Name: "seed_points_per_second", Up: seedPointsPerSecond, Down: clearPointsPerSecond},
+	{Version: 26, Name: "add_min_run_duration", Up: addMinRunDuration, Down: dropMinRunDuration},
+}
+
+// RunMigrations applies all pending database migrations
+func RunMigrations(db *pgxpool.Pool) error {
+	_, err := NewMigrator(db).Up(context.Background())
+	return err
 }
 
 // Database schema migrations
@@ -47,6 +64,10 @@ CREATE TABLE IF NOT EXISTS sessions (
 );
 `
 
+const dropSessionsTable = `
+DROP TABLE IF EXISTS sessions;
+`
+
 const createGamesTable = `
 CREATE TABLE IF NOT EXISTS games (
     id VARCHAR(50) PRIMARY KEY,
@@ -57,6 +78,10 @@ CREATE TABLE IF NOT EXISTS games (
 );
 `
 
+const dropGamesTable = `
+DROP TABLE IF EXISTS games;
+`
+
 const createScoresTable = `
 CREATE TABLE IF NOT EXISTS scores (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -67,6 +92,10 @@ CREATE TABLE IF NOT EXISTS scores (
 );
 `
 
+const dropScoresTable = `
+DROP TABLE IF EXISTS scores;
+`
+
 const createIndexes = `
 CREATE INDEX IF NOT EXISTS idx_game_score ON scores(game_id, score DESC);
 CREATE INDEX IF NOT EXISTS idx_session_game ON scores(session_id, game_id);
@@ -74,6 +103,13 @@ CREATE INDEX IF NOT EXISTS idx_session_token ON sessions(session_token);
 CREATE INDEX IF NOT EXISTS idx_session_active ON sessions(last_active);
 `
 
+const dropIndexes = `
+DROP INDEX IF EXISTS idx_game_score;
+DROP INDEX IF EXISTS idx_session_game;
+DROP INDEX IF EXISTS idx_session_token;
+DROP INDEX IF EXISTS idx_session_active;
+`
+
 const insertInitialGames = `
 INSERT INTO games (id, name, category) VALUES
     ('snake', 'Snake', 'arcade'),
@@ -112,4 +148,607 @@ INSERT INTO games (id, name, category) VALUES
     ('road-racer', 'Road Racer', 'racing'),
     ('speed-chase', 'Speed Chase', 'racing')
 ON CONFLICT (id) DO NOTHING;
-`
\ No newline at end of file
+`
+
+// deleteInitialGames removes the seeded catalogue. It fails while scores
+// still reference a game, which is intentional: rolling back seed data
+// must not silently orphan player history.
+const deleteInitialGames = `
+DELETE FROM games WHERE id IN (
+    'snake', 'tetris', 'pong', 'breakout', 'pacman', 'space-invaders',
+    'asteroids', 'frogger', 'centipede', 'missile-command', 'galaga',
+    'defender', 'phoenix', 'laser-defense', 'missile-defense',
+    'centipede-shooter', 'game2048', 'sudoku', 'connect-four', 'match3',
+    'sliding-puzzle', 'sokoban', 'tennis', 'basketball', 'bowling', 'soccer',
+    'golf', 'air-hockey', 'circuit-racer', 'desert-rally', 'drag-racing',
+    'f1-racing', 'mountain-racing', 'road-racer', 'speed-chase'
+);
+`
+
+// createLeaderboardArchives stores the final standings of closed daily,
+// weekly and monthly periods. Rows are copied rather than referenced so the
+// archive survives sessions and scores being deleted.
+const createLeaderboardArchives = `
+CREATE TABLE leaderboard_archives (
+    game_id VARCHAR(50) NOT NULL REFERENCES games(id),
+    period VARCHAR(10) NOT NULL,
+    period_key VARCHAR(10) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    rank INTEGER NOT NULL,
+    score_id UUID NOT NULL,
+    session_id UUID,
+    score INTEGER NOT NULL,
+    achieved_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (game_id, period, period_key, rank)
+);
+CREATE INDEX idx_archive_period_start ON leaderboard_archives(game_id, period, period_start DESC);
+CREATE INDEX idx_scores_achieved_at ON scores(achieved_at);
+`
+
+const dropLeaderboardArchives = `
+DROP INDEX IF EXISTS idx_scores_achieved_at;
+DROP TABLE IF EXISTS leaderboard_archives;
+`
+
+// addGameScoring records how each game's scores are ranked and displayed.
+// Every seeded game submits points, Golf, Drag Racing, Sudoku and Sliding
+// Puzzle included; games won by the lowest value (strokes, elapsed time,
+// moves) would rank ascending.
+const addGameScoring = `
+ALTER TABLE games
+    ADD COLUMN score_direction VARCHAR(10) NOT NULL DEFAULT 'higher'
+        CHECK (score_direction IN ('higher', 'lower')),
+    ADD COLUMN score_unit VARCHAR(20) NOT NULL DEFAULT 'points',
+    ADD COLUMN score_format VARCHAR(20) NOT NULL DEFAULT 'number';
+`
+
+const dropGameScoring = `
+ALTER TABLE games
+    DROP COLUMN IF EXISTS score_direction,
+    DROP COLUMN IF EXISTS score_unit,
+    DROP COLUMN IF EXISTS score_format;
+`
+
+// createRunTickets stores the single-use tickets issued when a game starts,
+// which every score submission must redeem, and a review queue of the
+// submissions that failed verification
+const createRunTickets = `
+CREATE TABLE run_tickets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    game_id VARCHAR(50) NOT NULL REFERENCES games(id),
+    signing_key VARCHAR(64) NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+CREATE INDEX idx_run_tickets_session ON run_tickets(session_id, expires_at);
+
+CREATE TABLE rejected_submissions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID REFERENCES sessions(id) ON DELETE SET NULL,
+    game_id VARCHAR(50),
+    score INTEGER,
+    run_ticket TEXT,
+    reason VARCHAR(30) NOT NULL,
+    rejected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_rejected_submissions_rejected_at ON rejected_submissions(rejected_at DESC);
+`
+
+const dropRunTickets = `
+DROP TABLE IF EXISTS rejected_submissions;
+DROP TABLE IF EXISTS run_tickets;
+`
+
+// addScorePlausibility adds per-game bounds on submitted scores and lets
+// scores that break them be kept out of the leaderboards as flagged.
+// NULL limits are not enforced.
+const addScorePlausibility = `
+ALTER TABLE games
+    ADD COLUMN max_score INTEGER,
+    ADD COLUMN max_points_per_second DOUBLE PRECISION,
+    ADD COLUMN score_granularity INTEGER NOT NULL DEFAULT 1 CHECK (score_granularity > 0);
+
+UPDATE games SET score_granularity = 10 WHERE id IN ('breakout', 'pong');
+UPDATE games SET max_score = 100000 WHERE id = 'pong';
+UPDATE games SET score_granularity = 100, max_score = 100000 WHERE id = 'connect-four';
+
+ALTER TABLE scores
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'accepted'
+        CHECK (status IN ('accepted', 'flagged')),
+    ADD COLUMN flag_reason VARCHAR(30);
+CREATE INDEX idx_scores_flagged ON scores(achieved_at DESC) WHERE status = 'flagged';
+`
+
+const dropScorePlausibility = `
+DROP INDEX IF EXISTS idx_scores_flagged;
+ALTER TABLE scores
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS flag_reason;
+ALTER TABLE games
+    DROP COLUMN IF EXISTS max_score,
+    DROP COLUMN IF EXISTS max_points_per_second,
+    DROP COLUMN IF EXISTS score_granularity;
+`
+
+// createScoreReplays seeds the run tickets of replay-verified puzzles and
+// keeps the move list behind every verified score
+const createScoreReplays = `
+ALTER TABLE run_tickets ADD COLUMN seed BIGINT;
+
+CREATE TABLE score_replays (
+    score_id UUID PRIMARY KEY REFERENCES scores(id) ON DELETE CASCADE,
+    seed BIGINT NOT NULL,
+    difficulty VARCHAR(10),
+    moves TEXT NOT NULL,
+    move_count INTEGER NOT NULL,
+    solved BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const dropScoreReplays = `
+DROP TABLE IF EXISTS score_replays;
+ALTER TABLE run_tickets DROP COLUMN IF EXISTS seed;
+`
+
+// addScoreMetadata records how each score was achieved and which modes,
+// difficulties and statistics each game accepts. stats_schema maps a stat
+// name to its {"min", "max"} bounds.
+const addScoreMetadata = `
+ALTER TABLE games
+    ADD COLUMN modes TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN difficulties TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN stats_schema JSONB NOT NULL DEFAULT '{}';
+
+UPDATE games SET modes = '{marathon,sprint,ultra}',
+    stats_schema = '{"lines": {"min": 0, "max": 100000}}' WHERE id = 'tetris';
+UPDATE games SET difficulties = '{easy,medium,hard}',
+    stats_schema = '{"mistakes": {"min": 0, "max": 1000}}' WHERE id = 'sudoku';
+UPDATE games SET stats_schema = '{"kills": {"min": 0, "max": 100000}, "waves": {"min": 0, "max": 10000}}'
+    WHERE id IN ('space-invaders', 'galaga');
+
+ALTER TABLE scores
+    ADD COLUMN duration_ms INTEGER CHECK (duration_ms >= 0),
+    ADD COLUMN level INTEGER CHECK (level >= 0),
+    ADD COLUMN difficulty VARCHAR(20),
+    ADD COLUMN game_mode VARCHAR(20),
+    ADD COLUMN stats JSONB;
+CREATE INDEX idx_scores_variant ON scores(game_id, game_mode, difficulty)
+    WHERE game_mode IS NOT NULL OR difficulty IS NOT NULL;
+`
+
+const dropScoreMetadata = `
+DROP INDEX IF EXISTS idx_scores_variant;
+ALTER TABLE scores
+    DROP COLUMN IF EXISTS duration_ms,
+    DROP COLUMN IF EXISTS level,
+    DROP COLUMN IF EXISTS difficulty,
+    DROP COLUMN IF EXISTS game_mode,
+    DROP COLUMN IF EXISTS stats;
+ALTER TABLE games
+    DROP COLUMN IF EXISTS modes,
+    DROP COLUMN IF EXISTS difficulties,
+    DROP COLUMN IF EXISTS stats_schema;
+`
+
+// createSubmissionKeys remembers each session's idempotency keys and the
+// response their submission received, so retries are not scored twice
+const createSubmissionKeys = `
+CREATE TABLE submission_keys (
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(100) NOT NULL,
+    score_id UUID REFERENCES scores(id) ON DELETE CASCADE,
+    response JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, idempotency_key)
+);
+`
+
+const dropSubmissionKeys = `
+DROP TABLE IF EXISTS submission_keys;
+`
+
+// addImportedScores allows unverified scores carried over from the browser's
+// localStorage, once per session
+const addImportedScores = `
+ALTER TABLE scores DROP CONSTRAINT scores_status_check;
+ALTER TABLE scores ADD CONSTRAINT scores_status_check
+    CHECK (status IN ('accepted', 'flagged', 'imported'));
+
+ALTER TABLE sessions ADD COLUMN scores_imported_at TIMESTAMP;
+`
+
+const dropImportedScores = `
+ALTER TABLE sessions DROP COLUMN IF EXISTS scores_imported_at;
+
+DELETE FROM scores WHERE status = 'imported';
+ALTER TABLE scores DROP CONSTRAINT scores_status_check;
+ALTER TABLE scores ADD CONSTRAINT scores_status_check
+    CHECK (status IN ('accepted', 'flagged'));
+`
+
+// createGameSaves stores each session's named save slots per game. data is
+// the gzip-compressed JSON state and size its uncompressed length.
+const createGameSaves = `
+CREATE TABLE game_saves (
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    game_id VARCHAR(50) NOT NULL REFERENCES games(id),
+    slot VARCHAR(32) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    schema_version INTEGER NOT NULL DEFAULT 0,
+    data BYTEA NOT NULL,
+    size INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, game_id, slot)
+);
+`
+
+const dropGameSaves = `
+DROP TABLE IF EXISTS game_saves;
+`
+
+// createGameSaveRevisions keeps the latest versions of every save slot,
+// including the current one, so a slot can be restored
+const createGameSaveRevisions = `
+CREATE TABLE game_save_revisions (
+    session_id UUID NOT NULL,
+    game_id VARCHAR(50) NOT NULL,
+    slot VARCHAR(32) NOT NULL,
+    version INTEGER NOT NULL,
+    schema_version INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    size INTEGER NOT NULL,
+    saved_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (session_id, game_id, slot, version),
+    FOREIGN KEY (session_id, game_id, slot)
+        REFERENCES game_saves(session_id, game_id, slot) ON DELETE CASCADE
+);
+
+INSERT INTO game_save_revisions (session_id, game_id, slot, version, schema_version, data, size, saved_at)
+SELECT session_id, game_id, slot, version, schema_version, data, size, updated_at
+FROM game_saves;
+`
+
+const dropGameSaveRevisions = `
+DROP TABLE IF EXISTS game_save_revisions;
+`
+
+// addSessionRevocation lets sessions be ended before they expire
+const addSessionRevocation = `
+ALTER TABLE sessions ADD COLUMN revoked_at TIMESTAMP;
+`
+
+const dropSessionRevocation = `
+ALTER TABLE sessions DROP COLUMN IF EXISTS revoked_at;
+`
+
+// createPlayers adds the players sessions belong to. Every existing session
+// becomes an anonymous player of its own, sharing its ID, and scores and
+// archived standings move from sessions to players. Deleting a session no
+// longer deletes its scores.
+const createPlayers = `
+CREATE TABLE players (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username VARCHAR(32),
+    password_hash TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    registered_at TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_players_username ON players(LOWER(username));
+
+INSERT INTO players (id, created_at)
+SELECT id, created_at FROM sessions;
+
+ALTER TABLE sessions ADD COLUMN player_id UUID REFERENCES players(id) ON DELETE CASCADE;
+UPDATE sessions SET player_id = id;
+ALTER TABLE sessions ALTER COLUMN player_id SET NOT NULL;
+CREATE INDEX idx_sessions_player ON sessions(player_id);
+
+ALTER TABLE scores ADD COLUMN player_id UUID REFERENCES players(id) ON DELETE CASCADE;
+UPDATE scores SET player_id = session_id;
+CREATE INDEX idx_player_game ON scores(player_id, game_id);
+ALTER TABLE scores DROP CONSTRAINT IF EXISTS scores_session_id_fkey;
+ALTER TABLE scores ADD CONSTRAINT scores_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE SET NULL;
+
+ALTER TABLE leaderboard_archives RENAME COLUMN session_id TO player_id;
+`
+
+const dropPlayers = `
+ALTER TABLE leaderboard_archives RENAME COLUMN player_id TO session_id;
+
+ALTER TABLE scores DROP CONSTRAINT IF EXISTS scores_session_id_fkey;
+ALTER TABLE scores ADD CONSTRAINT scores_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
+DROP INDEX IF EXISTS idx_player_game;
+ALTER TABLE scores DROP COLUMN IF EXISTS player_id;
+
+DROP INDEX IF EXISTS idx_sessions_player;
+ALTER TABLE sessions DROP COLUMN IF EXISTS player_id;
+
+DROP TABLE IF EXISTS players;
+`
+
+// keySavesOnPlayers moves save slots from sessions to players, so a player
+// sees the same saves on every device. Existing slots keep their rows as
+// each session's player shares its ID.
+const keySavesOnPlayers = `
+ALTER TABLE game_saves DROP CONSTRAINT IF EXISTS game_saves_session_id_fkey;
+ALTER TABLE game_saves RENAME COLUMN session_id TO player_id;
+ALTER TABLE game_saves ADD CONSTRAINT game_saves_player_id_fkey
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE;
+ALTER TABLE game_save_revisions RENAME COLUMN session_id TO player_id;
+`
+
+// keySavesOnSessions drops the saves of players that no session shares an ID with
+const keySavesOnSessions = `
+DELETE FROM game_saves WHERE player_id NOT IN (SELECT id FROM sessions);
+ALTER TABLE game_save_revisions RENAME COLUMN player_id TO session_id;
+ALTER TABLE game_saves DROP CONSTRAINT IF EXISTS game_saves_player_id_fkey;
+ALTER TABLE game_saves RENAME COLUMN player_id TO session_id;
+ALTER TABLE game_saves ADD CONSTRAINT game_saves_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
+`
+
+// createPlayerProfiles stores the public profile shown beside a player's
+// scores. name_key is the display name folded for comparison, so names that
+// only differ in case, punctuation or look-alike digits collide. Names an
+// admin has reset are kept in blocked_display_names so they cannot be taken
+// again.
+const createPlayerProfiles = `
+CREATE TABLE player_profiles (
+    player_id UUID PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+    display_name VARCHAR(24),
+    name_key VARCHAR(24),
+    avatar VARCHAR(32),
+    country CHAR(2),
+    bio VARCHAR(160),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    moderated_at TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_profiles_name_key ON player_profiles(name_key);
+
+CREATE TABLE blocked_display_names (
+    name_key VARCHAR(24) PRIMARY KEY,
+    display_name VARCHAR(24) NOT NULL,
+    blocked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const dropPlayerProfiles = `
+DROP TABLE IF EXISTS blocked_display_names;
+DROP TABLE IF EXISTS player_profiles;
+`
+
+// createPlayerStats keeps running totals of every player's accepted scores,
+// updated with each insert, so a player's overview never scans their
+// history. recent_scores holds the latest scores per game, newest first.
+// Both tables are backfilled from scores; streaks count days by the date
+// part of achieved_at.
+const createPlayerStats = `
+CREATE TABLE player_game_stats (
+    player_id UUID NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    game_id VARCHAR(50) NOT NULL REFERENCES games(id),
+    plays INTEGER NOT NULL DEFAULT 0,
+    total_duration_ms BIGINT NOT NULL DEFAULT 0,
+    best_score INTEGER NOT NULL,
+    best_rank INTEGER,
+    recent_scores INTEGER[] NOT NULL DEFAULT '{}',
+    first_played_at TIMESTAMP NOT NULL,
+    last_played_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (player_id, game_id)
+);
+
+CREATE TABLE player_stats (
+    player_id UUID PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+    current_streak INTEGER NOT NULL DEFAULT 0,
+    longest_streak INTEGER NOT NULL DEFAULT 0,
+    last_played_on DATE NOT NULL
+);
+
+INSERT INTO player_game_stats
+    (player_id, game_id, plays, total_duration_ms, best_score, recent_scores, first_played_at, last_played_at)
+SELECT s.player_id, s.game_id, COUNT(*), COALESCE(SUM(s.duration_ms), 0),
+       CASE WHEN g.score_direction = 'lower' THEN MIN(s.score) ELSE MAX(s.score) END,
+       (ARRAY_AGG(s.score ORDER BY s.achieved_at DESC))[1:10],
+       MIN(s.achieved_at), MAX(s.achieved_at)
+FROM scores s
+JOIN games g ON g.id = s.game_id
+WHERE s.status = 'accepted' AND s.player_id IS NOT NULL
+GROUP BY s.player_id, s.game_id, g.score_direction;
+
+WITH days AS (
+    SELECT DISTINCT player_id, achieved_at::date AS day
+    FROM scores
+    WHERE status = 'accepted' AND player_id IS NOT NULL
+), runs AS (
+    SELECT player_id, day, day - (ROW_NUMBER() OVER (PARTITION BY player_id ORDER BY day))::int AS run
+    FROM days
+), streaks AS (
+    SELECT player_id, COUNT(*) AS length, MAX(day) AS last_day
+    FROM runs
+    GROUP BY player_id, run
+)
+INSERT INTO player_stats (player_id, current_streak, longest_streak, last_played_on)
+SELECT player_id, (ARRAY_AGG(length ORDER BY last_day DESC))[1], MAX(length), MAX(last_day)
+FROM streaks
+GROUP BY player_id;
+`
+
+const dropPlayerStats = `
+DROP TABLE IF EXISTS player_stats;
+DROP TABLE IF EXISTS player_game_stats;
+`
+
+// indexPlayerHistory serves a player's scores newest first, across games and
+// within a date range
+const indexPlayerHistory = `
+CREATE INDEX idx_player_achieved_at ON scores(player_id, achieved_at DESC, id DESC);
+`
+
+const dropPlayerHistoryIndex = `
+DROP INDEX IF EXISTS idx_player_achieved_at;
+`
+
+// createAchievements declares achievements as rules over a player's stats
+// and records who unlocked them. A rule of one kind measures the games named
+// by game_id or category, or every game if neither is set:
+//   - score: a best score of at least threshold (at most, for games won by
+//     the lowest score) in any of them
+//   - plays: threshold plays across them
+//   - play_all: threshold plays of each of them
+//   - rank: an all-time rank of threshold or better in any of them
+//   - streak: threshold consecutive days played
+//
+// Rules whose backfilled_at is NULL are unlocked for players whose history
+// already meets them when the server next starts. score_id is the score
+// that unlocked an achievement, NULL for backfilled unlocks.
+const createAchievements = `
+CREATE TABLE achievements (
+    id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(200) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('score', 'plays', 'play_all', 'rank', 'streak')),
+    game_id VARCHAR(50) REFERENCES games(id),
+    category VARCHAR(20),
+    threshold INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP,
+    backfilled_at TIMESTAMP,
+    CHECK (game_id IS NULL OR category IS NULL)
+);
+
+CREATE TABLE player_achievements (
+    player_id UUID NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    achievement_id VARCHAR(50) NOT NULL REFERENCES achievements(id) ON DELETE CASCADE,
+    unlocked_at TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP,
+    score_id UUID REFERENCES scores(id) ON DELETE SET NULL,
+    PRIMARY KEY (player_id, achievement_id)
+);
+CREATE INDEX idx_player_achievements_achievement ON player_achievements(achievement_id);
+
+INSERT INTO achievements (id, name, description, kind, game_id, category, threshold) VALUES
+    ('first-credit', 'First Credit', 'Finish a game of anything', 'plays', NULL, NULL, 1),
+    ('regular', 'Regular', 'Play 100 games', 'plays', NULL, NULL, 100),
+    ('tile-2048', '2048', 'Score 2048 in 2048', 'score', 'game2048', NULL, 2048),
+    ('tetris-100k', 'Line Clearer', 'Score 100,000 in Tetris', 'score', 'tetris', NULL, 100000),
+    ('arcade-tour', 'Arcade Tour', 'Play every arcade game', 'play_all', NULL, 'arcade', 1),
+    ('grand-tour', 'Grand Tour', 'Play every game', 'play_all', NULL, NULL, 1),
+    ('pole-position', 'Pole Position', 'Reach the top 10 in any racing game', 'rank', NULL, 'racing', 10),
+    ('champion', 'Champion', 'Hold first place in any game', 'rank', NULL, NULL, 1),
+    ('week-streak', 'Week Streak', 'Play on 7 days in a row', 'streak', NULL, NULL, 7),
+    ('month-streak', 'Month Streak', 'Play on 30 days in a row', 'streak', NULL, NULL, 30);
+`
+
+const dropAchievements = `
+DROP TABLE IF EXISTS player_achievements;
+DROP TABLE IF EXISTS achievements;
+`
+
+// createXPLedger credits players with experience for their accepted scores.
+// Each game's curve turns the percentile a score reaches among the other
+// players' bests into xp_min to xp_max XP, weighted by the exponent
+// xp_curve. xp_ledger records every credit and player_xp the running total.
+const createXPLedger = `
+ALTER TABLE games
+    ADD COLUMN xp_min INTEGER NOT NULL DEFAULT 10 CHECK (xp_min >= 0),
+    ADD COLUMN xp_max INTEGER NOT NULL DEFAULT 100,
+    ADD COLUMN xp_curve DOUBLE PRECISION NOT NULL DEFAULT 2 CHECK (xp_curve > 0),
+    ADD CONSTRAINT games_xp_range CHECK (xp_max >= xp_min);
+
+UPDATE games SET xp_min = 20, xp_max = 150 WHERE id IN ('sudoku', 'sokoban');
+UPDATE games SET xp_curve = 1 WHERE id IN ('pong', 'connect-four', 'air-hockey');
+
+CREATE TABLE xp_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    player_id UUID NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    score_id UUID UNIQUE REFERENCES scores(id) ON DELETE SET NULL,
+    game_id VARCHAR(50) NOT NULL REFERENCES games(id),
+    amount INTEGER NOT NULL,
+    percentile DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP
+);
+CREATE INDEX idx_xp_ledger_player ON xp_ledger(player_id, created_at DESC);
+
+CREATE TABLE player_xp (
+    player_id UUID PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+    total_xp BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP
+);
+CREATE INDEX idx_player_xp_total ON player_xp(total_xp DESC, updated_at);
+
+CREATE INDEX idx_player_game_stats_game ON player_game_stats(game_id, best_score);
+`
+
+const dropXPLedger = `
+DROP INDEX IF EXISTS idx_player_game_stats_game;
+DROP TABLE IF EXISTS player_xp;
+DROP TABLE IF EXISTS xp_ledger;
+ALTER TABLE games
+    DROP CONSTRAINT IF EXISTS games_xp_range,
+    DROP COLUMN IF EXISTS xp_min,
+    DROP COLUMN IF EXISTS xp_max,
+    DROP COLUMN IF EXISTS xp_curve;
+`
+
+// createGameSaveVersions records the last version each save slot was given.
+// It outlives the slot, so a slot deleted and created again carries on from
+// its old version rather than reusing one a client may still hold.
+const createGameSaveVersions = `
+CREATE TABLE game_save_versions (
+    player_id UUID NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    game_id VARCHAR(50) NOT NULL REFERENCES games(id),
+    slot VARCHAR(32) NOT NULL,
+    version INTEGER NOT NULL,
+    PRIMARY KEY (player_id, game_id, slot)
+);
+
+INSERT INTO game_save_versions (player_id, game_id, slot, version)
+SELECT player_id, game_id, slot, version
+FROM game_saves;
+`
+
+const dropGameSaveVersions = `
+DROP TABLE IF EXISTS game_save_versions;
+`
+
+// seedPointsPerSecond caps how fast the games whose points build up through a
+// run can score, at several times the rate of strong play. Races, matches,
+// puzzles proven by replay and games scored once at the end are bounded by
+// their other rules instead.
+const seedPointsPerSecond = `
+UPDATE games SET max_points_per_second = 25 WHERE id = 'snake';
+UPDATE games SET max_points_per_second = 150 WHERE id IN ('pacman', 'frogger');
+UPDATE games SET max_points_per_second = 250 WHERE id IN ('breakout', 'asteroids', 'centipede', 'missile-command');
+UPDATE games SET max_points_per_second = 300 WHERE id = 'match3';
+UPDATE games SET max_points_per_second = 500
+WHERE id IN ('space-invaders', 'galaga', 'defender', 'phoenix', 'laser-defense', 'missile-defense', 'centipede-shooter');
+UPDATE games SET max_points_per_second = 1000 WHERE id = 'tetris';
+`
+
+const clearPointsPerSecond = `
+UPDATE games SET max_points_per_second = NULL;
+`
+
+// addMinRunDuration records the shortest plausible time from starting a run
+// of each game to submitting its score. Timed matches and puzzles take far
+// longer than an arcade game lost in its first seconds.
+const addMinRunDuration = `
+ALTER TABLE games
+    ADD COLUMN min_run_ms INTEGER NOT NULL DEFAULT 5000 CHECK (min_run_ms >= 0);
+
+UPDATE games SET min_run_ms = 3000
+WHERE category IN ('arcade', 'shooter') OR id IN ('road-racer', 'speed-chase', 'desert-rally', 'mountain-racing');
+UPDATE games SET min_run_ms = 10000 WHERE id IN ('game2048', 'sliding-puzzle', 'sokoban', 'connect-four', 'match3', 'tetris');
+UPDATE games SET min_run_ms = 15000 WHERE id IN ('pong', 'tennis', 'air-hockey', 'bowling', 'circuit-racer');
+UPDATE games SET min_run_ms = 30000 WHERE id IN ('golf', 'f1-racing');
+UPDATE games SET min_run_ms = 60000 WHERE id IN ('sudoku', 'basketball', 'soccer');
+`
+
+const dropMinRunDuration = `
+ALTER TABLE games DROP COLUMN IF EXISTS min_run_ms;
+`
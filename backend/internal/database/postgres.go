@@ -20,6 +20,10 @@ func NewPostgresConnection(databaseURL string) (*pgxpool.Pool, error) {
 	config.MaxConnLifetime = time.Hour      // Rotate connections
 	config.MaxConnIdleTime = 30 * time.Minute // Close idle connections
 
+	// Timestamps are stored as UTC wall clock (LOCALTIMESTAMP and
+	// CURRENT_TIMESTAMP into timestamp columns), whatever the server's TimeZone
+	config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
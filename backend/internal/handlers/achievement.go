@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"retro-games-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAchievements lists every achievement and how many players have unlocked it
+func (h *Handlers) GetAchievements(c *gin.Context) {
+	achievements, err := h.achievementService.GetAchievements(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch achievements",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, achievements)
+}
+
+// GetAchievement gets one achievement and how many players have unlocked it
+func (h *Handlers) GetAchievement(c *gin.Context) {
+	achievement, err := h.achievementService.GetAchievement(c.Request.Context(), c.Param("achievementId"))
+	if errors.Is(err, services.ErrUnknownAchievement) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Achievement not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch achievement",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, achievement)
+}
+
+// GetMyAchievements lists every achievement with when the caller unlocked it
+func (h *Handlers) GetMyAchievements(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	achievements, err := h.achievementService.GetPlayerAchievements(c.Request.Context(), identity.PlayerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch achievements",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, achievements)
+}
@@ -17,4 +17,16 @@ func (h *Handlers) GetGames(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, games)
-}
\ No newline at end of file
+}
+// GetCategories returns all game categories with their game counts
+func (h *Handlers) GetCategories(c *gin.Context) {
+	categories, err := h.gameService.GetCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch categories",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
@@ -7,22 +7,37 @@ import (
 // Handlers contains all HTTP handlers
 type Handlers struct {
 	sessionService     *services.SessionService
+	playerService      *services.PlayerService
+	profileService     *services.ProfileService
 	gameService        *services.GameService
 	scoreService       *services.ScoreService
 	leaderboardService *services.LeaderboardService
+	saveService        *services.SaveService
+	statsService       *services.StatsService
+	achievementService *services.AchievementService
 }
 
 // New creates a new handlers instance
 func New(
 	sessionService *services.SessionService,
+	playerService *services.PlayerService,
+	profileService *services.ProfileService,
 	gameService *services.GameService,
 	scoreService *services.ScoreService,
 	leaderboardService *services.LeaderboardService,
+	saveService *services.SaveService,
+	statsService *services.StatsService,
+	achievementService *services.AchievementService,
 ) *Handlers {
 	return &Handlers{
 		sessionService:     sessionService,
+		playerService:      playerService,
+		profileService:     profileService,
 		gameService:        gameService,
 		scoreService:       scoreService,
 		leaderboardService: leaderboardService,
+		saveService:        saveService,
+		statsService:       statsService,
+		achievementService: achievementService,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRecentRuns pages through the caller's scores across every game, newest first
+func (h *Handlers) GetRecentRuns(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	query, ok := parseHistoryQuery(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.scoreService.GetRecentRuns(c.Request.Context(), identity.PlayerID, query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid cursor",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch recent runs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// parseHistoryQuery reads the sort, filter and paging query parameters of a
+// score history, responding with 400 if any is invalid
+func parseHistoryQuery(c *gin.Context) (models.ScoreHistoryQuery, bool) {
+	sort, err := models.ParseHistorySort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Sort must be score or date",
+		})
+		return models.ScoreHistoryQuery{}, false
+	}
+
+	// Parse limit parameter (default to 10)
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	// Parse the optional date range, both ends inclusive
+	var dates [2]*time.Time
+	for i, name := range []string{"from", "to"} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "From and to must be dates as YYYY-MM-DD",
+			})
+			return models.ScoreHistoryQuery{}, false
+		}
+		dates[i] = &date
+	}
+	if dates[0] != nil && dates[1] != nil && dates[1].Before(*dates[0]) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "From must not be after to",
+		})
+		return models.ScoreHistoryQuery{}, false
+	}
+
+	return models.ScoreHistoryQuery{
+		Variant: parseVariant(c),
+		From:    dates[0],
+		To:      dates[1],
+		Sort:    sort,
+		Cursor:  c.Query("cursor"),
+		Limit:   limit,
+	}, true
+}
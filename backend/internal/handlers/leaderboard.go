@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,8 +29,83 @@ func (h *Handlers) GetGameLeaderboard(c *gin.Context) {
 		}
 	}
 
+	mode, period, ok := parseBoard(c)
+	if !ok {
+		return
+	}
+
 	// Get leaderboard
-	leaderboard, err := h.leaderboardService.GetGameLeaderboard(c.Request.Context(), gameID, limit)
+	leaderboard, err := h.leaderboardService.GetGameLeaderboard(c.Request.Context(), gameID, parseVariant(c), mode, period, limit)
+	if errors.Is(err, services.ErrUnknownGame) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Game not found",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrUnknownVariant) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Game does not offer this mode or difficulty",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch leaderboard",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// GetLeaderboardAroundMe gets the leaderboard entries around the caller's best score
+func (h *Handlers) GetLeaderboardAroundMe(c *gin.Context) {
+	gameID := c.Param("gameId")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Game ID is required",
+		})
+		return
+	}
+
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Parse range parameter (default to 5 entries either side)
+	radius := 5
+	if rangeStr := c.Query("range"); rangeStr != "" {
+		if parsedRange, err := strconv.Atoi(rangeStr); err == nil && parsedRange >= 0 && parsedRange <= 50 {
+			radius = parsedRange
+		}
+	}
+
+	mode, period, ok := parseBoard(c)
+	if !ok {
+		return
+	}
+
+	// Get leaderboard window
+	leaderboard, err := h.leaderboardService.GetLeaderboardAroundPlayer(c.Request.Context(), identity.PlayerID, gameID, parseVariant(c), mode, period, radius)
+	if errors.Is(err, services.ErrUnknownGame) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Game not found",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrUnknownVariant) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Game does not offer this mode or difficulty",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrNoScores) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No scores recorded for this game",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch leaderboard",
@@ -39,6 +118,25 @@ func (h *Handlers) GetGameLeaderboard(c *gin.Context) {
 
 // GetGlobalLeaderboard gets the global leaderboard across all games
 func (h *Handlers) GetGlobalLeaderboard(c *gin.Context) {
+	h.respondCrossGameLeaderboard(c, c.Query("category"))
+}
+
+// GetCategoryLeaderboard ranks players across the games of one category
+func (h *Handlers) GetCategoryLeaderboard(c *gin.Context) {
+	category := c.Param("category")
+	if category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Category is required",
+		})
+		return
+	}
+
+	h.respondCrossGameLeaderboard(c, category)
+}
+
+// respondCrossGameLeaderboard writes the points-based ranking across all
+// games, or across one category's games if category is set
+func (h *Handlers) respondCrossGameLeaderboard(c *gin.Context, category string) {
 	// Parse limit parameter (default to 20)
 	limit := 20
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -47,8 +145,27 @@ func (h *Handlers) GetGlobalLeaderboard(c *gin.Context) {
 		}
 	}
 
-	// Get global leaderboard
-	leaderboard, err := h.leaderboardService.GetGlobalLeaderboard(c.Request.Context(), limit)
+	period, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+
+	method, err := models.ParseGlobalRankingMethod(c.Query("method"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Method must be rank_points or percentile",
+		})
+		return
+	}
+
+	// Get global leaderboard, optionally limited to one category
+	leaderboard, err := h.leaderboardService.GetGlobalLeaderboard(c.Request.Context(), category, method, period, limit)
+	if errors.Is(err, services.ErrUnknownCategory) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Category not found",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch global leaderboard",
@@ -57,4 +174,149 @@ func (h *Handlers) GetGlobalLeaderboard(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, leaderboard)
-}
\ No newline at end of file
+}
+
+// GetLeaderboardArchive lists the winners of a game's past periods
+func (h *Handlers) GetLeaderboardArchive(c *gin.Context) {
+	gameID := c.Param("gameId")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Game ID is required",
+		})
+		return
+	}
+
+	period, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+	if period == models.PeriodAllTime {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Period must be daily, weekly or monthly",
+		})
+		return
+	}
+
+	// Parse limit parameter (default to 10 periods)
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	// Parse top parameter (default to 3 winners per period)
+	top := 3
+	if topStr := c.Query("top"); topStr != "" {
+		if parsedTop, err := strconv.Atoi(topStr); err == nil && parsedTop > 0 && parsedTop <= 10 {
+			top = parsedTop
+		}
+	}
+
+	archive, err := h.leaderboardService.GetArchivedPeriods(c.Request.Context(), gameID, period, limit, top)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch leaderboard archive",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, archive)
+}
+
+// GetArchivedStandings gets the final standings of one closed period
+func (h *Handlers) GetArchivedStandings(c *gin.Context) {
+	gameID := c.Param("gameId")
+	periodKey := c.Param("periodKey")
+	if gameID == "" || periodKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Game ID and period key are required",
+		})
+		return
+	}
+
+	period, err := models.ParseLeaderboardPeriod(c.Param("period"))
+	if err != nil || period == models.PeriodAllTime {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Period must be daily, weekly or monthly",
+		})
+		return
+	}
+
+	standings, err := h.leaderboardService.GetArchivedStandings(c.Request.Context(), gameID, period, periodKey)
+	if errors.Is(err, services.ErrPeriodNotArchived) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No archived standings for this period",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch archived standings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, standings)
+}
+
+// parsePeriod reads the period query parameter, responding with 400 if it is invalid
+func parsePeriod(c *gin.Context) (models.LeaderboardPeriod, bool) {
+	period, err := models.ParseLeaderboardPeriod(c.Query("period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Period must be daily, weekly, monthly or all-time",
+		})
+		return "", false
+	}
+	return period, true
+}
+
+// parseBoard reads the mode and period query parameters, responding with 400
+// if either is invalid
+func parseBoard(c *gin.Context) (models.LeaderboardMode, models.LeaderboardPeriod, bool) {
+	mode, err := models.ParseLeaderboardMode(c.Query("mode"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Mode must be best_per_player or all_runs",
+		})
+		return "", "", false
+	}
+
+	period, ok := parsePeriod(c)
+	if !ok {
+		return "", "", false
+	}
+
+	return mode, period, true
+}
+
+// parseVariant reads the optional game_mode and difficulty filters; the
+// service checks them against the game
+func parseVariant(c *gin.Context) models.ScoreVariant {
+	return models.ScoreVariant{
+		GameMode:   c.Query("game_mode"),
+		Difficulty: c.Query("difficulty"),
+	}
+}
+
+// GetXPLeaderboard ranks players by lifetime XP
+func (h *Handlers) GetXPLeaderboard(c *gin.Context) {
+	// Parse limit parameter (default to 20)
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	leaderboard, err := h.leaderboardService.GetXPLeaderboard(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch XP leaderboard",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
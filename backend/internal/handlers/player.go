@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurrentPlayer describes the player the caller's session belongs to
+func (h *Handlers) GetCurrentPlayer(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	player, err := h.playerService.GetPlayer(c.Request.Context(), identity.PlayerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch player",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, player)
+}
+
+// RegisterPlayer gives the caller's anonymous player a username and password
+func (h *Handlers) RegisterPlayer(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Username must be 3-32 characters and password 8-128 characters",
+		})
+		return
+	}
+
+	player, err := h.playerService.Register(c.Request.Context(), identity.PlayerID, req)
+	if errors.Is(err, services.ErrInvalidUsername) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Usernames may only contain letters, digits, '_' and '-'",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Username is already taken",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrAlreadyRegistered) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Player is already registered",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to register player",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, player)
+}
+
+// Login opens a new session for a registered player
+func (h *Handlers) Login(c *gin.Context) {
+	// Parse request body
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Username and password are required",
+		})
+		return
+	}
+
+	session, err := h.playerService.Login(c.Request.Context(), req, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid username or password",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to log in",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// CreatePairingCode issues a code another device can redeem to join the
+// caller's player
+func (h *Handlers) CreatePairingCode(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	pairing, err := h.playerService.CreatePairingCode(c.Request.Context(), identity.PlayerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create pairing code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, pairing)
+}
+
+// RedeemPairingCode opens a new session for the player a pairing code was
+// issued to
+func (h *Handlers) RedeemPairingCode(c *gin.Context) {
+	// Parse request body
+	var req models.RedeemPairingCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Pairing code is required",
+		})
+		return
+	}
+
+	session, err := h.playerService.RedeemPairingCode(c.Request.Context(), req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, services.ErrTooManyPairingAttempts) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many pairing attempts; try again later",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidPairingCode) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Pairing code is invalid or has expired",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to redeem pairing code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyProfile returns the caller's profile
+func (h *Handlers) GetMyProfile(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(c.Request.Context(), identity.PlayerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateMyProfile replaces the caller's profile
+func (h *Handlers) UpdateMyProfile(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(c.Request.Context(), identity.PlayerID, req)
+	var refused *services.ProfileRefusedError
+	if errors.As(err, &refused) {
+		status := http.StatusBadRequest
+		if refused.Reason == services.RefuseTaken {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error":  "Profile refused",
+			"field":  refused.Field,
+			"reason": refused.Reason,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetAvatars lists the pixel avatars a profile can choose from
+func (h *Handlers) GetAvatars(c *gin.Context) {
+	c.JSON(http.StatusOK, models.AvatarsResponse{Avatars: services.Avatars})
+}
+
+// ResetDisplayName clears an offending display name and blocks it (admin only)
+func (h *Handlers) ResetDisplayName(c *gin.Context) {
+	profile, err := h.profileService.ResetDisplayName(c.Request.Context(), c.Param("displayName"))
+	if errors.Is(err, services.ErrProfileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No profile has this display name",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset display name",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSaves lists the caller's save slots for a game
+func (h *Handlers) ListSaves(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	saves, err := h.saveService.ListSaves(c.Request.Context(), identity.PlayerID, c.Param("gameId"))
+	if err != nil {
+		respondSaveError(c, err, "Failed to list saves")
+		return
+	}
+
+	c.JSON(http.StatusOK, saves)
+}
+
+// GetSave loads one of the caller's save slots
+func (h *Handlers) GetSave(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	save, err := h.saveService.GetSave(c.Request.Context(), identity.PlayerID, c.Param("gameId"), c.Param("slot"))
+	if err != nil {
+		respondSaveError(c, err, "Failed to fetch save")
+		return
+	}
+
+	c.Header("ETag", saveETag(save.Version))
+	c.JSON(http.StatusOK, save)
+}
+
+// saveRequestOverhead is the room left in a save request's body for
+// everything around its data
+const saveRequestOverhead = 1 << 10
+
+// PutSave creates or updates one of the caller's save slots. Updates must
+// send the version last read as If-Match; "*" overwrites any version.
+func (h *Handlers) PutSave(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	expected, ok := parseIfMatch(c, models.SaveNew)
+	if !ok {
+		return
+	}
+
+	// Parse request body, refusing oversized saves before decoding them
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxSaveSize+saveRequestOverhead)
+	var req models.PutSaveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondSaveError(c, services.ErrSaveTooLarge, "")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	saved, created, err := h.saveService.PutSave(c.Request.Context(), identity.PlayerID, c.Param("gameId"), c.Param("slot"), expected, req)
+	var conflict *services.SaveConflictError
+	if errors.As(err, &conflict) {
+		respondSaveConflict(c, conflict, &req, expected)
+		return
+	}
+	if err != nil {
+		respondSaveError(c, err, "Failed to write save")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.Header("ETag", saveETag(saved.Version))
+	c.JSON(status, saved)
+}
+
+// DeleteSave deletes one of the caller's save slots, checking If-Match if sent
+func (h *Handlers) DeleteSave(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	expected, ok := parseIfMatch(c, models.SaveAnyVersion)
+	if !ok {
+		return
+	}
+
+	err := h.saveService.DeleteSave(c.Request.Context(), identity.PlayerID, c.Param("gameId"), c.Param("slot"), expected)
+	if err != nil {
+		respondSaveError(c, err, "Failed to delete save")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSaveRevisions lists the kept versions of one of the caller's save slots
+func (h *Handlers) ListSaveRevisions(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.saveService.ListRevisions(c.Request.Context(), identity.PlayerID, c.Param("gameId"), c.Param("slot"))
+	if err != nil {
+		respondSaveError(c, err, "Failed to list save revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetSaveRevision loads one kept version of one of the caller's save slots
+func (h *Handlers) GetSaveRevision(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	version, ok := parseSaveVersion(c)
+	if !ok {
+		return
+	}
+
+	revision, err := h.saveService.GetRevision(c.Request.Context(), identity.PlayerID, c.Param("gameId"), c.Param("slot"), version)
+	if err != nil {
+		respondSaveError(c, err, "Failed to fetch save revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// RestoreSave makes a kept version of one of the caller's save slots current
+// again, checking If-Match if sent
+func (h *Handlers) RestoreSave(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	version, ok := parseSaveVersion(c)
+	if !ok {
+		return
+	}
+
+	expected, ok := parseIfMatch(c, models.SaveAnyVersion)
+	if !ok {
+		return
+	}
+
+	saved, err := h.saveService.RestoreSave(c.Request.Context(), identity.PlayerID, c.Param("gameId"), c.Param("slot"), version, expected)
+	if err != nil {
+		respondSaveError(c, err, "Failed to restore save")
+		return
+	}
+
+	c.Header("ETag", saveETag(saved.Version))
+	c.JSON(http.StatusOK, saved)
+}
+
+// currentSession validates the caller's session and returns who it belongs
+// to, responding with 401 if it is invalid
+func (h *Handlers) currentSession(c *gin.Context) (models.Identity, bool) {
+	// Get session token from context (set by auth middleware)
+	sessionToken, exists := c.Get("session_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Session token required",
+		})
+		return models.Identity{}, false
+	}
+
+	// Validate session and get the session's player
+	identity, err := h.sessionService.ValidateSession(c.Request.Context(), sessionToken.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid session",
+		})
+		return models.Identity{}, false
+	}
+
+	return identity, true
+}
+
+// respondSaveError maps a save service error to its response
+func respondSaveError(c *gin.Context, err error, message string) {
+	var conflict *services.SaveConflictError
+	switch {
+	case errors.Is(err, services.ErrUnknownGame):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+	case errors.Is(err, services.ErrSaveNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Save not found"})
+	case errors.Is(err, services.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Save revision not found"})
+	case errors.Is(err, services.ErrInvalidSaveSlot):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slot names must be 1-32 lowercase letters, digits, '-' or '_'"})
+	case errors.Is(err, services.ErrSaveTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Save data must be at most 64 KiB"})
+	case errors.Is(err, services.ErrTooManySaveSlots):
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many save slots for this game"})
+	case errors.Is(err, services.ErrSaveVersionRequired):
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Save exists; send its version as If-Match"})
+	case errors.As(err, &conflict):
+		respondSaveConflict(c, conflict, nil, 0)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// respondSaveConflict responds with 409, returning the slot's current
+// version alongside the rejected write, if any
+func respondSaveConflict(c *gin.Context, conflict *services.SaveConflictError, yours *models.PutSaveRequest, basedOn int) {
+	if basedOn < 0 {
+		basedOn = 0
+	}
+	c.Header("ETag", saveETag(conflict.Current.Version))
+	c.JSON(http.StatusConflict, models.SaveConflictResponse{
+		Error:   "Save has changed since it was read",
+		Current: conflict.Current,
+		Yours:   yours,
+		BasedOn: basedOn,
+	})
+}
+
+// parseIfMatch reads the save version in the If-Match header, returning
+// fallback if there is none, and responding with 400 if it is malformed
+func parseIfMatch(c *gin.Context, fallback int) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return fallback, true
+	}
+	if header == "*" {
+		return models.SaveAnyVersion, true
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "If-Match must be a save version",
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+// parseSaveVersion reads the version path parameter, responding with 400 if
+// it is malformed
+func parseSaveVersion(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Version must be a positive number",
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+// saveETag formats a save version as an ETag
+func saveETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// saveContext returns a test context for a request carrying the given If-Match
+func saveContext(ifMatch string) (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/v1/saves/tetris/slot-1", nil)
+	if ifMatch != "" {
+		c.Request.Header.Set("If-Match", ifMatch)
+	}
+	return c, recorder
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int
+		ok     bool
+	}{
+		{"", models.SaveNew, true},
+		{"*", models.SaveAnyVersion, true},
+		{`"3"`, 3, true},
+		{`W/"3"`, 3, true},
+		{"12", 12, true},
+		{` "7" `, 7, true},
+		{`"0"`, 0, false},
+		{`"-1"`, 0, false},
+		{`"abc"`, 0, false},
+	}
+
+	for _, tt := range tests {
+		c, recorder := saveContext(tt.header)
+		got, ok := parseIfMatch(c, models.SaveNew)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("If-Match %q: got %d, %v, want %d, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+		if !tt.ok && recorder.Code != http.StatusBadRequest {
+			t.Errorf("If-Match %q: status %d, want 400", tt.header, recorder.Code)
+		}
+	}
+}
+
+func TestRespondSaveError(t *testing.T) {
+	conflict := &services.SaveConflictError{Current: &models.SaveResponse{SaveSlot: models.SaveSlot{Slot: "slot-1", Version: 4}}}
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{services.ErrUnknownGame, http.StatusNotFound},
+		{services.ErrSaveNotFound, http.StatusNotFound},
+		{services.ErrRevisionNotFound, http.StatusNotFound},
+		{services.ErrInvalidSaveSlot, http.StatusBadRequest},
+		{services.ErrSaveTooLarge, http.StatusRequestEntityTooLarge},
+		{services.ErrTooManySaveSlots, http.StatusConflict},
+		{services.ErrSaveVersionRequired, http.StatusPreconditionRequired},
+		{fmt.Errorf("failed to delete save: %w", conflict), http.StatusConflict},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		c, recorder := saveContext("")
+		respondSaveError(c, tt.err, "Failed to write save")
+		if recorder.Code != tt.want {
+			t.Errorf("%v: status %d, want %d", tt.err, recorder.Code, tt.want)
+		}
+	}
+}
+
+func TestRespondSaveConflict(t *testing.T) {
+	current := &models.SaveResponse{
+		GameID:   "tetris",
+		SaveSlot: models.SaveSlot{Slot: "slot-1", Version: 5},
+		Data:     json.RawMessage(`{"level":9}`),
+	}
+	yours := &models.PutSaveRequest{SchemaVersion: 1, Data: json.RawMessage(`{"level":7}`)}
+
+	tests := []struct {
+		name        string
+		yours       *models.PutSaveRequest
+		basedOn     int
+		wantBasedOn int
+	}{
+		{"stale write", yours, 3, 3},
+		{"overwrite", yours, models.SaveAnyVersion, 0},
+		{"delete", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, recorder := saveContext("")
+			respondSaveConflict(c, &services.SaveConflictError{Current: current}, tt.yours, tt.basedOn)
+
+			if recorder.Code != http.StatusConflict {
+				t.Fatalf("status %d, want 409", recorder.Code)
+			}
+			if etag := recorder.Header().Get("ETag"); etag != `"5"` {
+				t.Errorf("ETag %s, want \"5\"", etag)
+			}
+
+			var body models.SaveConflictResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if body.Current == nil || body.Current.Version != 5 || string(body.Current.Data) != `{"level":9}` {
+				t.Errorf("current = %+v, want version 5 with its data", body.Current)
+			}
+			if (body.Yours == nil) != (tt.yours == nil) {
+				t.Errorf("yours = %+v, want %+v", body.Yours, tt.yours)
+			}
+			if body.BasedOn != tt.wantBasedOn {
+				t.Errorf("based_on = %d, want %d", body.BasedOn, tt.wantBasedOn)
+			}
+		})
+	}
+}
@@ -1,48 +1,136 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
-	// "github.com/google/uuid"
+	"github.com/google/uuid"
 )
 
 // SubmitScore handles score submission
 func (h *Handlers) SubmitScore(c *gin.Context) {
-	// Get session token from context (set by auth middleware)
-	sessionToken, exists := c.Get("session_token")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Session token required",
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.ScoreSubmissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
 		})
 		return
 	}
 
-	// Validate session and get session ID
-	sessionID, err := h.sessionService.ValidateSession(c.Request.Context(), sessionToken.(string))
+	// The Idempotency-Key header stands in for client_run_id
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		if len(key) > 100 || (req.ClientRunID != "" && req.ClientRunID != key) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must match client_run_id and be at most 100 characters",
+			})
+			return
+		}
+		req.ClientRunID = key
+	}
+
+	// Submit score
+	response, err := h.scoreService.SubmitScore(c.Request.Context(), identity, req)
+	if errors.Is(err, services.ErrUnknownGame) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown game",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrIdempotencyKeyReused) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency key was already used for a different submission",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidMetadata) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	var rejected *services.SubmissionRejectedError
+	if errors.As(err, &rejected) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "Score submission rejected",
+			"reason": rejected.Reason,
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid session",
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to submit score",
 		})
 		return
 	}
 
+	c.JSON(http.StatusCreated, response)
+}
+
+// SubmitScoreBatch handles a batch of scores played offline
+func (h *Handlers) SubmitScoreBatch(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
 	// Parse request body
-	var req models.ScoreSubmissionRequest
+	var req models.BatchScoreRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
+			"error": "Request must carry between 1 and 50 valid scores",
 		})
 		return
 	}
 
-	// Submit score
-	response, err := h.scoreService.SubmitScore(c.Request.Context(), sessionID, req.GameID, req.Score)
+	// Submit scores
+	response, err := h.scoreService.SubmitBatch(c.Request.Context(), identity, req.Scores)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to submit score",
+			"error": "Failed to submit scores",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ImportScores imports the high scores the frontend kept in localStorage
+func (h *Handlers) ImportScores(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.ImportScoresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Request must carry between 1 and 100 scores",
+		})
+		return
+	}
+
+	// Import scores
+	response, err := h.scoreService.ImportScores(c.Request.Context(), identity, req.Scores)
+	if errors.Is(err, services.ErrAlreadyImported) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Scores have already been imported for this session",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to import scores",
 		})
 		return
 	}
@@ -50,7 +138,7 @@ func (h *Handlers) SubmitScore(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
-// GetUserScores gets all scores for a user and specific game
+// GetUserScores pages through the caller's scores for a specific game
 func (h *Handlers) GetUserScores(c *gin.Context) {
 	gameID := c.Param("gameId")
 	if gameID == "" {
@@ -60,32 +148,98 @@ func (h *Handlers) GetUserScores(c *gin.Context) {
 		return
 	}
 
-	// Get session token from context (set by auth middleware)
-	sessionToken, exists := c.Get("session_token")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Session token required",
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	query, ok := parseHistoryQuery(c)
+	if !ok {
+		return
+	}
+	query.GameID = gameID
+
+	// Get a page of the caller's scores
+	scores, err := h.scoreService.GetUserScores(c.Request.Context(), identity.PlayerID, query)
+	if errors.Is(err, services.ErrUnknownGame) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Game not found",
 		})
 		return
 	}
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid cursor",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch scores",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, scores)
+}
+
+// StartRun issues a run ticket for a game the session is about to play
+func (h *Handlers) StartRun(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
 
-	// Validate session and get session ID
-	sessionID, err := h.sessionService.ValidateSession(c.Request.Context(), sessionToken.(string))
+	// Parse request body
+	var req models.StartRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	// Issue run ticket
+	ticket, err := h.scoreService.StartRun(c.Request.Context(), identity.SessionID, req.GameID)
+	if errors.Is(err, services.ErrUnknownGame) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown game",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start run",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// GetReplay returns the stored replay behind a verified puzzle score
+func (h *Handlers) GetReplay(c *gin.Context) {
+	scoreID, err := uuid.Parse(c.Param("scoreId"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid session",
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid score ID",
 		})
 		return
 	}
 
-	// Get user scores
-	scores, err := h.scoreService.GetUserScores(c.Request.Context(), sessionID, gameID)
+	replay, err := h.scoreService.GetReplay(c.Request.Context(), scoreID)
+	if errors.Is(err, services.ErrReplayNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Replay not found",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch scores",
+			"error": "Failed to fetch replay",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, scores)
+	c.JSON(http.StatusOK, replay)
 }
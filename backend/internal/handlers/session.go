@@ -27,4 +27,57 @@ func (h *Handlers) CreateSession(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, session)
-}
\ No newline at end of file
+}
+
+// GetCurrentSession describes the caller's session
+func (h *Handlers) GetCurrentSession(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.sessionService.GetSession(c.Request.Context(), identity.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// RefreshSession rotates the caller's session token and restarts its expiry
+func (h *Handlers) RefreshSession(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.sessionService.RefreshSession(c.Request.Context(), identity, c.GetString("session_token"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to refresh session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// RevokeSession logs the caller out, ending their session
+func (h *Handlers) RevokeSession(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(c.Request.Context(), identity.SessionID, c.GetString("session_token")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke session",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
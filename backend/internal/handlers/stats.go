@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyStats returns an overview of the caller's play across every game
+func (h *Handlers) GetMyStats(c *gin.Context) {
+	identity, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.statsService.GetPlayerStats(c.Request.Context(), identity.PlayerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch player stats",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth guards admin endpoints with a shared token sent in the
+// X-Admin-Token header. With no token configured every request is refused.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Admin API is disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		given := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Admin token required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
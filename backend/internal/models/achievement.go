@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// AchievementKind is what an achievement's rule measures
+type AchievementKind string
+
+// Supported achievement rules. Each measures the games named by an
+// achievement's GameID or Category, or every game if neither is set.
+const (
+	// AchievementScore needs a best score of at least Threshold in any of
+	// the games, or at most Threshold in games won by the lowest score
+	AchievementScore AchievementKind = "score"
+	// AchievementPlays needs Threshold plays across the games
+	AchievementPlays AchievementKind = "plays"
+	// AchievementPlayAll needs Threshold plays of each of the games
+	AchievementPlayAll AchievementKind = "play_all"
+	// AchievementRank needs an all-time rank of Threshold or better in any
+	// of the games
+	AchievementRank AchievementKind = "rank"
+	// AchievementStreak needs Threshold consecutive days played
+	AchievementStreak AchievementKind = "streak"
+)
+
+// Achievement is a goal a player unlocks once their stats meet its rule.
+// UnlockedBy counts the players who have it, and UnlockedPercent is their
+// share of players who have an accepted score.
+type Achievement struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	Kind            AchievementKind `json:"kind"`
+	GameID          string          `json:"game_id,omitempty"`
+	Category        string          `json:"category,omitempty"`
+	Threshold       int             `json:"threshold"`
+	UnlockedBy      int             `json:"unlocked_by"`
+	UnlockedPercent float64         `json:"unlocked_percent"`
+}
+
+// AchievementsResponse is the achievement catalogue
+type AchievementsResponse struct {
+	Achievements []Achievement `json:"achievements"`
+	Total        int           `json:"total"`
+}
+
+// PlayerAchievement is an achievement and when a player unlocked it, if
+// they have
+type PlayerAchievement struct {
+	Achievement
+	UnlockedAt *time.Time `json:"unlocked_at,omitempty"`
+}
+
+// PlayerAchievementsResponse lists every achievement, unlocked ones first
+type PlayerAchievementsResponse struct {
+	Achievements []PlayerAchievement `json:"achievements"`
+	Unlocked     int                 `json:"unlocked"`
+	Total        int                 `json:"total"`
+}
+
+// AchievementUnlock announces an achievement a score has just unlocked
+type AchievementUnlock struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+}
@@ -1,18 +1,153 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ScoreDirection says whether a game is won by the highest or lowest score
+type ScoreDirection string
+
+// Supported score directions
+const (
+	ScoreHigherIsBetter ScoreDirection = "higher"
+	ScoreLowerIsBetter  ScoreDirection = "lower"
+)
+
+// Sign returns 1 for higher-is-better games and -1 for lower-is-better
+// games, so that score*sign always ranks descending
+func (d ScoreDirection) Sign() int {
+	if d == ScoreLowerIsBetter {
+		return -1
+	}
+	return 1
+}
+
+// Supported score units
+const (
+	ScoreUnitPoints       = "points"
+	ScoreUnitMilliseconds = "milliseconds"
+	ScoreUnitStrokes      = "strokes"
+	ScoreUnitMoves        = "moves"
+)
+
+// Supported score display formats
+const (
+	ScoreFormatNumber   = "number"   // Plain integer, e.g. 12,450
+	ScoreFormatDuration = "duration" // Milliseconds shown as m:ss.SSS
+)
+
+// Scoring describes how a game's scores are ranked and displayed
+type Scoring struct {
+	Direction ScoreDirection `json:"direction" db:"score_direction"`
+	Unit      string         `json:"unit" db:"score_unit"`
+	Format    string         `json:"format" db:"score_format"`
+}
+
+// ScoreRules bounds the scores a game can plausibly produce. A zero
+// MaxScore or MaxPointsPerSecond means no limit. MinRunDuration is the
+// shortest plausible time from starting a run to submitting its score.
+type ScoreRules struct {
+	MaxScore           int           `db:"max_score"`
+	MaxPointsPerSecond float64       `db:"max_points_per_second"`
+	Granularity        int           `db:"score_granularity"`
+	MinRunDuration     time.Duration `db:"min_run_ms"`
+}
+
+// XPCurve turns the percentile a score reaches among a game's players, from
+// 0 to 1, into between Min and Max XP. Exponent shapes the curve: above 1,
+// most of the XP is kept for the best scores.
+type XPCurve struct {
+	Min      int     `db:"xp_min"`
+	Max      int     `db:"xp_max"`
+	Exponent float64 `db:"xp_curve"`
+}
+
+// XP returns the XP a score at percentile earns
+func (c XPCurve) XP(percentile float64) int {
+	return c.Min + int(math.Round(float64(c.Max-c.Min)*math.Pow(percentile, c.Exponent)))
+}
+
+// StatRule bounds one of a game's per-run statistics. A zero Max means no limit.
+type StatRule struct {
+	Min int `json:"min"`
+	Max int `json:"max,omitempty"`
+}
+
+// MetadataRules lists the metadata a game's scores may carry: the modes and
+// difficulties it offers and a schema of the integer statistics (lines,
+// kills, ...) it reports
+type MetadataRules struct {
+	Modes        []string            `json:"modes,omitempty" db:"modes"`
+	Difficulties []string            `json:"difficulties,omitempty" db:"difficulties"`
+	Stats        map[string]StatRule `json:"stats,omitempty" db:"stats_schema"`
+}
+
+// Validate checks a score's metadata against the rules
+func (m MetadataRules) Validate(meta ScoreMetadata) error {
+	if meta.Mode != "" && !contains(m.Modes, meta.Mode) {
+		return fmt.Errorf("unknown mode %q", meta.Mode)
+	}
+	if meta.Difficulty != "" && !contains(m.Difficulties, meta.Difficulty) {
+		return fmt.Errorf("unknown difficulty %q", meta.Difficulty)
+	}
+	for name, value := range meta.Stats {
+		rule, ok := m.Stats[name]
+		if !ok {
+			return fmt.Errorf("unknown stat %q", name)
+		}
+		if value < rule.Min || (rule.Max > 0 && value > rule.Max) {
+			return fmt.Errorf("stat %q out of range", name)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether a leaderboard can be filtered to the variant
+func (m MetadataRules) Allows(variant ScoreVariant) bool {
+	return (variant.GameMode == "" || contains(m.Modes, variant.GameMode)) &&
+		(variant.Difficulty == "" || contains(m.Difficulties, variant.Difficulty))
+}
+
+// contains reports whether values includes value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
 
 // Game represents a game configuration
 type Game struct {
-	ID        string    `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Category  string    `json:"category" db:"category"`
-	Enabled   bool      `json:"enabled" db:"enabled"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID        string        `json:"id" db:"id"`
+	Name      string        `json:"name" db:"name"`
+	Category  string        `json:"category" db:"category"`
+	Enabled   bool          `json:"enabled" db:"enabled"`
+	Scoring   Scoring       `json:"scoring"`
+	Metadata  MetadataRules `json:"metadata"`
+	Rules     ScoreRules    `json:"-"`
+	XP        XPCurve       `json:"-"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
 }
 
 // GamesListResponse represents the response for listing games
 type GamesListResponse struct {
 	Games []Game `json:"games"`
 	Total int    `json:"total"`
-}
\ No newline at end of file
+}
+
+// Category represents a game category and the enabled games in it
+type Category struct {
+	ID        string   `json:"id"`
+	GameCount int      `json:"game_count"`
+	GameIDs   []string `json:"game_ids"`
+}
+
+// CategoriesListResponse represents the response for listing categories
+type CategoriesListResponse struct {
+	Categories []Category `json:"categories"`
+	Total      int        `json:"total"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestXPCurve(t *testing.T) {
+	standard := XPCurve{Min: 10, Max: 100, Exponent: 2}
+	linear := XPCurve{Min: 10, Max: 100, Exponent: 1}
+	puzzle := XPCurve{Min: 20, Max: 150, Exponent: 2}
+
+	tests := []struct {
+		name       string
+		curve      XPCurve
+		percentile float64
+		want       int
+	}{
+		{"standard worst", standard, 0, 10},
+		{"standard median", standard, 0.5, 33},
+		{"standard top decile", standard, 0.9, 83},
+		{"standard best", standard, 1, 100},
+		{"linear median", linear, 0.5, 55},
+		{"linear best", linear, 1, 100},
+		{"puzzle median", puzzle, 0.5, 53},
+		{"puzzle best", puzzle, 1, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.curve.XP(tt.percentile); got != tt.want {
+				t.Errorf("XP(%v) = %d, want %d", tt.percentile, got, tt.want)
+			}
+		})
+	}
+}
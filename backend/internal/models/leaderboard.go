@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// LeaderboardPeriod is the time window a leaderboard ranks scores over
+type LeaderboardPeriod string
+
+// Supported leaderboard periods
+const (
+	PeriodDaily   LeaderboardPeriod = "daily"
+	PeriodWeekly  LeaderboardPeriod = "weekly"
+	PeriodMonthly LeaderboardPeriod = "monthly"
+	PeriodAllTime LeaderboardPeriod = "all-time"
+)
+
+// ClosablePeriods lists the periods whose final standings are archived
+var ClosablePeriods = []LeaderboardPeriod{PeriodDaily, PeriodWeekly, PeriodMonthly}
+
+// ParseLeaderboardPeriod validates a period query value, defaulting to all-time
+func ParseLeaderboardPeriod(value string) (LeaderboardPeriod, error) {
+	switch LeaderboardPeriod(value) {
+	case "", PeriodAllTime:
+		return PeriodAllTime, nil
+	case PeriodDaily, PeriodWeekly, PeriodMonthly:
+		return LeaderboardPeriod(value), nil
+	}
+	return "", fmt.Errorf("unknown leaderboard period %q", value)
+}
+
+// LeaderboardMode controls whether a player may appear more than once on a leaderboard
+type LeaderboardMode string
+
+// Supported leaderboard modes
+const (
+	ModeBestPerPlayer LeaderboardMode = "best_per_player"
+	ModeAllRuns       LeaderboardMode = "all_runs"
+)
+
+// ParseLeaderboardMode validates a mode query value, defaulting to best per player
+func ParseLeaderboardMode(value string) (LeaderboardMode, error) {
+	switch LeaderboardMode(value) {
+	case "", ModeBestPerPlayer:
+		return ModeBestPerPlayer, nil
+	case ModeAllRuns:
+		return ModeAllRuns, nil
+	}
+	return "", fmt.Errorf("unknown leaderboard mode %q", value)
+}
+
+// ArchivedPeriod represents the final standings of a closed leaderboard period
+type ArchivedPeriod struct {
+	PeriodKey   string             `json:"period_key"`
+	PeriodStart time.Time          `json:"period_start"`
+	PeriodEnd   time.Time          `json:"period_end"`
+	Entries     []LeaderboardEntry `json:"entries"`
+}
+
+// ArchiveListResponse represents the winners of past leaderboard periods
+type ArchiveListResponse struct {
+	GameID  string            `json:"game_id"`
+	Period  LeaderboardPeriod `json:"period"`
+	Periods []ArchivedPeriod  `json:"periods"`
+	Total   int               `json:"total"`
+}
+
+// GlobalRankingMethod controls how per-game standings are converted into
+// points on the cross-game leaderboard
+type GlobalRankingMethod string
+
+// Supported global ranking methods
+const (
+	RankingRankPoints GlobalRankingMethod = "rank_points"
+	RankingPercentile GlobalRankingMethod = "percentile"
+)
+
+// ParseGlobalRankingMethod validates a method query value, defaulting to rank points
+func ParseGlobalRankingMethod(value string) (GlobalRankingMethod, error) {
+	switch GlobalRankingMethod(value) {
+	case "", RankingRankPoints:
+		return RankingRankPoints, nil
+	case RankingPercentile:
+		return RankingPercentile, nil
+	}
+	return "", fmt.Errorf("unknown ranking method %q", value)
+}
+
+// ScoreVariant narrows a leaderboard to scores of one game mode and/or
+// difficulty. Empty fields match every score.
+type ScoreVariant struct {
+	GameMode   string
+	Difficulty string
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity is who a validated session token belongs to. Scores, personal
+// bests and saves belong to the player; run tickets and idempotency keys to
+// the session.
+type Identity struct {
+	SessionID uuid.UUID
+	PlayerID  uuid.UUID
+}
+
+// RegisterRequest turns the caller's anonymous player into an account
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=32"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
+}
+
+// LoginRequest signs in to an account from a new device
+type LoginRequest struct {
+	Username string `json:"username" binding:"required,max=32"`
+	Password string `json:"password" binding:"required,max=128"`
+}
+
+// PlayerInfo describes the caller's player. Username is empty while the
+// player is anonymous.
+type PlayerInfo struct {
+	PlayerID     uuid.UUID  `json:"player_id"`
+	Username     string     `json:"username,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RegisteredAt *time.Time `json:"registered_at,omitempty"`
+}
+
+// PairingCodeResponse is a code another device can redeem, until ExpiresAt,
+// to join the caller's player
+type PairingCodeResponse struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RedeemPairingCodeRequest joins the player a pairing code was issued to
+type RedeemPairingCodeRequest struct {
+	Code string `json:"code" binding:"required,max=16"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Profile is the public face of a player, shown beside their scores. Every
+// field is optional; Avatar is one of the pixel avatars in AvatarsResponse
+// and Country an ISO 3166-1 alpha-2 code.
+type Profile struct {
+	PlayerID    uuid.UUID  `json:"player_id"`
+	DisplayName string     `json:"display_name,omitempty"`
+	Avatar      string     `json:"avatar,omitempty"`
+	Country     string     `json:"country,omitempty"`
+	Bio         string     `json:"bio,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// PlayerCard is the part of a player's profile shown on leaderboard entries
+type PlayerCard struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	Country     string `json:"country,omitempty"`
+}
+
+// UpdateProfileRequest replaces the caller's profile; empty fields are cleared
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name" binding:"max=24"`
+	Avatar      string `json:"avatar" binding:"max=32"`
+	Country     string `json:"country" binding:"max=2"`
+	Bio         string `json:"bio" binding:"max=160"`
+}
+
+// AvatarsResponse lists the pixel avatars a profile can choose from
+type AvatarsResponse struct {
+	Avatars []string `json:"avatars"`
+}
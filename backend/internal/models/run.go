@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StartRunRequest represents a request for a run ticket when a game starts
+type StartRunRequest struct {
+	GameID string `json:"game_id" binding:"required"`
+}
+
+// RunTicketResponse represents a run ticket. The signing key is only ever
+// returned here; the client signs its score submission with it. Seed is set
+// for replay-verified games and must be used to deal the starting position.
+type RunTicketResponse struct {
+	RunTicket  uuid.UUID `json:"run_ticket"`
+	GameID     string    `json:"game_id"`
+	SigningKey string    `json:"signing_key"`
+	Seed       *uint32   `json:"seed,omitempty"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ReplayInput is the recorded input of a replay-verified run
+type ReplayInput struct {
+	Moves      string `json:"moves" binding:"max=100000"`
+	Difficulty string `json:"difficulty,omitempty"`
+}
+
+// ReplayResponse represents a stored replay of a verified score
+type ReplayResponse struct {
+	ScoreID    uuid.UUID `json:"score_id"`
+	GameID     string    `json:"game_id"`
+	Score      int       `json:"score"`
+	Seed       uint32    `json:"seed"`
+	Difficulty string    `json:"difficulty,omitempty"`
+	Moves      string    `json:"moves"`
+	MoveCount  int       `json:"move_count"`
+	Solved     bool      `json:"solved"`
+	AchievedAt time.Time `json:"achieved_at"`
+}
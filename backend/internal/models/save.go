@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Expected versions for writing a save slot that are not a real version
+const (
+	SaveNew        = 0  // The slot must not exist yet
+	SaveAnyVersion = -1 // Overwrite whatever version the slot holds
+)
+
+// PutSaveRequest writes a save slot. Data is the game's own opaque JSON
+// state; SchemaVersion is the client's format version for it.
+type PutSaveRequest struct {
+	SchemaVersion int             `json:"schema_version" binding:"min=0"`
+	Data          json.RawMessage `json:"data" binding:"required"`
+}
+
+// SaveSlot describes a save slot without its data. Version increases with
+// every write, carrying on if the slot is deleted and created again, and
+// doubles as the slot's ETag.
+type SaveSlot struct {
+	Slot          string    `json:"slot"`
+	Version       int       `json:"version"`
+	SchemaVersion int       `json:"schema_version"`
+	Size          int       `json:"size"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SaveResponse is a save slot with its data
+type SaveResponse struct {
+	GameID string `json:"game_id"`
+	SaveSlot
+	Data json.RawMessage `json:"data"`
+}
+
+// SaveSlotsResponse lists a game's save slots
+type SaveSlotsResponse struct {
+	GameID string     `json:"game_id"`
+	Slots  []SaveSlot `json:"slots"`
+}
+
+// SaveRevisionsResponse lists the kept versions of a save slot, newest
+// first; Current is the version the slot holds
+type SaveRevisionsResponse struct {
+	GameID    string     `json:"game_id"`
+	Slot      string     `json:"slot"`
+	Current   int        `json:"current"`
+	Revisions []SaveSlot `json:"revisions"`
+}
+
+// SaveConflictResponse is returned when a save is written over a version
+// the client has not seen. Current is the slot as it stands and Yours the
+// rejected write, based on version BasedOn; the client resolves the
+// conflict by writing its choice with If-Match set to Current's version.
+type SaveConflictResponse struct {
+	Error   string          `json:"error"`
+	Current *SaveResponse   `json:"current"`
+	Yours   *PutSaveRequest `json:"yours,omitempty"`
+	BasedOn int             `json:"based_on,omitempty"`
+}
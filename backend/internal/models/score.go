@@ -1,67 +1,249 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Score statuses. Only accepted scores appear on leaderboards; imported
+// scores are unverified high scores carried over from the browser.
+const (
+	ScoreAccepted = "accepted"
+	ScoreFlagged  = "flagged"
+	ScoreImported = "imported"
+)
+
+// ScoreMetadata describes how a score was achieved. Mode, difficulty and
+// stats are validated against the game's MetadataRules.
+type ScoreMetadata struct {
+	DurationMs *int           `json:"duration_ms,omitempty" db:"duration_ms" binding:"omitempty,min=0"`
+	Level      *int           `json:"level,omitempty" db:"level" binding:"omitempty,min=0"`
+	Difficulty string         `json:"difficulty,omitempty" db:"difficulty"`
+	Mode       string         `json:"mode,omitempty" db:"game_mode"`
+	Stats      map[string]int `json:"stats,omitempty" db:"stats"`
+}
+
+// Variant returns the leaderboard variant the metadata belongs to
+func (m ScoreMetadata) Variant() ScoreVariant {
+	return ScoreVariant{GameMode: m.Mode, Difficulty: m.Difficulty}
+}
+
 // Score represents a game score record
 type Score struct {
 	ID         uuid.UUID `json:"id" db:"id"`
-	SessionID  uuid.UUID `json:"session_id" db:"session_id"`
+	PlayerID   uuid.UUID `json:"player_id" db:"player_id"`
 	GameID     string    `json:"game_id" db:"game_id"`
 	Score      int       `json:"score" db:"score"`
+	Status     string    `json:"status" db:"status"`
 	AchievedAt time.Time `json:"achieved_at" db:"achieved_at"`
+	ScoreMetadata
 }
 
-// ScoreSubmissionRequest represents a score submission request
+// ScoreSubmissionRequest represents a score submission request. RunTicket is
+// the ticket issued when the game started and Signature is the hex
+// HMAC-SHA256 of SignaturePayload keyed with the ticket's signing key.
+// Replay is required for replay-verified games. ClientRunID, also accepted
+// as the Idempotency-Key header, makes retries of the same submission safe.
 type ScoreSubmissionRequest struct {
-	GameID string `json:"game_id" binding:"required"`
-	Score  int    `json:"score" binding:"required,min=0,max=99999999"`
+	GameID      string       `json:"game_id" binding:"required"`
+	Score       int          `json:"score" binding:"required,min=0,max=99999999"`
+	RunTicket   string       `json:"run_ticket"`
+	Signature   string       `json:"signature"`
+	Replay      *ReplayInput `json:"replay,omitempty"`
+	ClientRunID string       `json:"client_run_id,omitempty" binding:"max=100"`
+	ScoreMetadata
+}
+
+// SignaturePayload returns the canonical string a submission's signature covers
+func (r ScoreSubmissionRequest) SignaturePayload() string {
+	return fmt.Sprintf("%s:%s:%d", r.RunTicket, r.GameID, r.Score)
 }
 
-// ScoreResponse represents the response after submitting a score
+// ScoreResponse represents the response after submitting a score. Rank is the
+// player's position on the all-time best-per-player leaderboard. Flagged
+// scores are kept for review but not ranked. Duplicate is set when a retried
+// submission gets back its original response. XP is what an accepted score
+// earned, including any level-ups, and Achievements lists those the score
+// unlocked.
 type ScoreResponse struct {
-	GameID       string    `json:"game_id"`
-	Score        int       `json:"score"`
-	PersonalBest int       `json:"personal_best"`
-	Rank         int       `json:"rank,omitempty"`
-	AchievedAt   time.Time `json:"achieved_at"`
+	GameID       string              `json:"game_id"`
+	Score        int                 `json:"score"`
+	Status       string              `json:"status"`
+	FlagReason   string              `json:"flag_reason,omitempty"`
+	PersonalBest int                 `json:"personal_best"`
+	Rank         int                 `json:"rank,omitempty"`
+	AchievedAt   time.Time           `json:"achieved_at"`
+	Duplicate    bool                `json:"duplicate,omitempty"`
+	XP           *XPGain             `json:"xp,omitempty"`
+	Achievements []AchievementUnlock `json:"achievements,omitempty"`
 }
 
-// UserScoresResponse represents the response for user's scores
+// UserScoresResponse represents a page of a player's scores. Total counts
+// the scores on this page; NextCursor fetches the next one and is empty on
+// the last page.
 type UserScoresResponse struct {
-	Scores []Score `json:"scores"`
-	Total  int     `json:"total"`
+	Scores     []Score `json:"scores"`
+	Total      int     `json:"total"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// HistorySort orders a player's score history
+type HistorySort string
+
+// Supported score history orders
+const (
+	SortByScore HistorySort = "score"
+	SortByDate  HistorySort = "date"
+)
+
+// ParseHistorySort validates a sort query value, defaulting to best score first
+func ParseHistorySort(value string) (HistorySort, error) {
+	switch HistorySort(value) {
+	case "", SortByScore:
+		return SortByScore, nil
+	case SortByDate:
+		return SortByDate, nil
+	}
+	return "", fmt.Errorf("unknown history sort %q", value)
+}
+
+// ScoreHistoryQuery selects a page of a player's scores. From and To are
+// inclusive dates; Cursor is the NextCursor of the previous page.
+type ScoreHistoryQuery struct {
+	GameID  string
+	Variant ScoreVariant
+	From    *time.Time
+	To      *time.Time
+	Sort    HistorySort
+	Cursor  string
+	Limit   int
 }
 
 // LeaderboardEntry represents a single leaderboard entry
 type LeaderboardEntry struct {
 	Rank       int       `json:"rank"`
 	Score      int       `json:"score"`
-	SessionID  string    `json:"session_id,omitempty"`
+	PlayerID   string    `json:"player_id,omitempty"`
 	AchievedAt time.Time `json:"achieved_at"`
+	IsCurrent  bool      `json:"is_current,omitempty"`
+	PlayerCard
 }
 
 // LeaderboardResponse represents the response for leaderboards
 type LeaderboardResponse struct {
-	GameID  string             `json:"game_id"`
-	Entries []LeaderboardEntry `json:"entries"`
-	Total   int                `json:"total"`
+	GameID      string             `json:"game_id"`
+	Mode        LeaderboardMode    `json:"mode"`
+	Period      LeaderboardPeriod  `json:"period"`
+	PeriodKey   string             `json:"period_key,omitempty"`
+	PeriodStart *time.Time         `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time         `json:"period_end,omitempty"`
+	Scoring     *Scoring           `json:"scoring,omitempty"`
+	GameMode    string             `json:"game_mode,omitempty"`
+	Difficulty  string             `json:"difficulty,omitempty"`
+	Entries     []LeaderboardEntry `json:"entries"`
+	Total       int                `json:"total"`
 }
 
-// GlobalLeaderboardEntry represents a global leaderboard entry
+// AroundMeResponse represents the leaderboard window around the caller's best score
+type AroundMeResponse struct {
+	GameID       string             `json:"game_id"`
+	Mode         LeaderboardMode    `json:"mode"`
+	Period       LeaderboardPeriod  `json:"period"`
+	PeriodKey    string             `json:"period_key,omitempty"`
+	Scoring      *Scoring           `json:"scoring,omitempty"`
+	GameMode     string             `json:"game_mode,omitempty"`
+	Difficulty   string             `json:"difficulty,omitempty"`
+	Rank         int                `json:"rank"`
+	Score        int                `json:"score"`
+	Percentile   float64            `json:"percentile"`
+	TotalEntries int                `json:"total_entries"`
+	Entries      []LeaderboardEntry `json:"entries"`
+}
+
+// GlobalLeaderboardEntry represents a player's standing across games. Points
+// are the sum of the points earned from the player's rank in each game.
 type GlobalLeaderboardEntry struct {
-	GameID     string    `json:"game_id"`
-	GameName   string    `json:"game_name"`
-	Score      int       `json:"score"`
-	SessionID  string    `json:"session_id,omitempty"`
-	AchievedAt time.Time `json:"achieved_at"`
+	Rank        int     `json:"rank"`
+	PlayerID    string  `json:"player_id,omitempty"`
+	Points      float64 `json:"points"`
+	GamesPlayed int     `json:"games_played"`
+	BestRank    int     `json:"best_rank"`
+	BestGameID  string  `json:"best_game_id"`
+	PlayerCard
 }
 
 // GlobalLeaderboardResponse represents the global leaderboard response
 type GlobalLeaderboardResponse struct {
-	Entries []GlobalLeaderboardEntry `json:"entries"`
-	Total   int                      `json:"total"`
-}
\ No newline at end of file
+	Category  string                   `json:"category,omitempty"`
+	Method    GlobalRankingMethod      `json:"method"`
+	Period    LeaderboardPeriod        `json:"period"`
+	PeriodKey string                   `json:"period_key,omitempty"`
+	Entries   []GlobalLeaderboardEntry `json:"entries"`
+	Total     int                      `json:"total"`
+}
+
+// ImportScoresRequest carries the high scores the frontend kept in
+// localStorage, keyed by their legacy storage key (e.g. "tetris_high_score")
+type ImportScoresRequest struct {
+	Scores map[string]int `json:"scores" binding:"required,min=1,max=100"`
+}
+
+// ImportedScore is a legacy high score stored for a game
+type ImportedScore struct {
+	Key    string `json:"key"`
+	GameID string `json:"game_id"`
+	Score  int    `json:"score"`
+}
+
+// SkippedScore is a legacy high score that could not be imported
+type SkippedScore struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// ImportScoresResponse reports the outcome of a legacy score import
+type ImportScoresResponse struct {
+	Imported []ImportedScore `json:"imported"`
+	Skipped  []SkippedScore  `json:"skipped"`
+}
+
+// Outcomes of one score in a batch submission
+const (
+	BatchRecorded  = "recorded"
+	BatchDuplicate = "duplicate"
+	BatchRejected  = "rejected"
+	BatchInvalid   = "invalid"
+)
+
+// BatchScoreItem is a score played offline. AchievedAt is when the run
+// ended, by the server's clock as estimated from the run ticket.
+type BatchScoreItem struct {
+	ScoreSubmissionRequest
+	AchievedAt *time.Time `json:"achieved_at" binding:"required"`
+}
+
+// BatchScoreRequest submits up to 50 scores played offline
+type BatchScoreRequest struct {
+	Scores []BatchScoreItem `json:"scores" binding:"required,min=1,max=50,dive"`
+}
+
+// BatchScoreResult is the outcome of one score in a batch, by its position
+// in the request. Score is set for recorded and duplicate scores, Reason for
+// rejected ones and Error for invalid ones.
+type BatchScoreResult struct {
+	Index  int            `json:"index"`
+	Result string         `json:"result"`
+	Score  *ScoreResponse `json:"score,omitempty"`
+	Reason string         `json:"reason,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// BatchScoreResponse reports the outcome of every score in a batch and the
+// achievements the batch unlocked
+type BatchScoreResponse struct {
+	Results      []BatchScoreResult  `json:"results"`
+	Recorded     int                 `json:"recorded"`
+	Achievements []AchievementUnlock `json:"achievements,omitempty"`
+}
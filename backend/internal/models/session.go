@@ -6,9 +6,10 @@ import (
 	"github.com/google/uuid"
 )
 
-// Session represents an anonymous user session
+// Session represents one device's session of a player
 type Session struct {
 	ID           uuid.UUID `json:"id" db:"id"`
+	PlayerID     uuid.UUID `json:"player_id" db:"player_id"`
 	SessionToken string    `json:"session_token" db:"session_token"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	LastActive   time.Time `json:"last_active" db:"last_active"`
@@ -26,4 +27,14 @@ type CreateSessionRequest struct {
 type SessionResponse struct {
 	SessionToken string    `json:"session_token"`
 	ExpiresAt    time.Time `json:"expires_at,omitempty"`
-}
\ No newline at end of file
+}
+
+// SessionInfo describes the caller's session. It expires at ExpiresAt
+// unless used again before then.
+type SessionInfo struct {
+	SessionID  uuid.UUID `json:"session_id"`
+	PlayerID   uuid.UUID `json:"player_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastActive time.Time `json:"last_active"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Directions a player's recent scores in a game can be heading
+const (
+	TrendImproving = "improving"
+	TrendSteady    = "steady"
+	TrendDeclining = "declining"
+)
+
+// PlayerStatsResponse is an overview of everything a player has played.
+// CurrentStreak counts consecutive days played up to today or yesterday.
+type PlayerStatsResponse struct {
+	XP                Progression       `json:"xp"`
+	TotalPlays        int               `json:"total_plays"`
+	TotalTimeMs       int64             `json:"total_time_ms"`
+	GamesPlayed       int               `json:"games_played"`
+	FavouriteCategory string            `json:"favourite_category,omitempty"`
+	CurrentStreak     int               `json:"current_streak"`
+	LongestStreak     int               `json:"longest_streak"`
+	LastPlayedOn      string            `json:"last_played_on,omitempty"`
+	Games             []PlayerGameStats `json:"games"`
+}
+
+// PlayerGameStats summarises a player's accepted scores in one game, most
+// recently played first. Rank is the player's current all-time position and
+// BestRank the highest they have held. Trend compares their latest scores
+// with the ones before, and is empty until there are enough of them;
+// TrendPercent is the change in the game's scoring direction.
+type PlayerGameStats struct {
+	GameID       string    `json:"game_id"`
+	Category     string    `json:"category"`
+	Plays        int       `json:"plays"`
+	TotalTimeMs  int64     `json:"total_time_ms"`
+	BestScore    int       `json:"best_score"`
+	Rank         int       `json:"rank,omitempty"`
+	BestRank     int       `json:"best_rank,omitempty"`
+	Trend        string    `json:"trend,omitempty"`
+	TrendPercent float64   `json:"trend_percent,omitempty"`
+	LastPlayedAt time.Time `json:"last_played_at"`
+}
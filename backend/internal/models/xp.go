@@ -0,0 +1,43 @@
+package models
+
+// Progression is a player's level, earned from their lifetime XP. Levels run
+// from 1 to the maximum; completing the last one earns a prestige and starts
+// again at level 1. LevelXP is the XP earned towards the next level, which
+// takes NextLevelXP.
+type Progression struct {
+	TotalXP     int64 `json:"total_xp"`
+	Prestige    int   `json:"prestige"`
+	Level       int   `json:"level"`
+	LevelXP     int64 `json:"level_xp"`
+	NextLevelXP int64 `json:"next_level_xp"`
+}
+
+// LevelUp is a level a player reached, within its prestige
+type LevelUp struct {
+	Prestige int `json:"prestige"`
+	Level    int `json:"level"`
+}
+
+// XPGain is the XP a score earned, from the percentile it reached among the
+// other players of its game, and the player's progression afterwards.
+// LevelUps lists every level the score took the player to.
+type XPGain struct {
+	Earned     int     `json:"earned"`
+	Percentile float64 `json:"percentile"`
+	Progression
+	LevelUps []LevelUp `json:"level_ups,omitempty"`
+}
+
+// XPLeaderboardEntry is a player's standing by lifetime XP
+type XPLeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	PlayerID string `json:"player_id,omitempty"`
+	Progression
+	PlayerCard
+}
+
+// XPLeaderboardResponse ranks players by lifetime XP
+type XPLeaderboardResponse struct {
+	Entries []XPLeaderboardEntry `json:"entries"`
+	Total   int                  `json:"total"`
+}
@@ -0,0 +1,147 @@
+package replay
+
+import (
+	"fmt"
+	"time"
+)
+
+// grid2048Size is the width and height of the 2048 board
+const grid2048Size = 4
+
+// min2048MoveTime is the least time a 2048 move takes, as tiles finish
+// sliding before the next move is read
+const min2048MoveTime = 100 * time.Millisecond
+
+// game2048 replays 2048. The board starts with two random tiles and gains one
+// after every move; each new tile fills a random empty cell, taken in
+// row-major order, and is a 2 with 90% probability or a 4 otherwise. The
+// score is the sum of every merged tile. Moves that leave the board
+// unchanged are not allowed, and the moves must fit within the run.
+type game2048 struct{}
+
+type board2048 [grid2048Size][grid2048Size]int
+
+func (game2048) Replay(in Input) (Result, error) {
+	dirs, err := parseDirections(in.Moves)
+	if err != nil {
+		return Result{}, err
+	}
+
+	r := newRNG(in.Seed)
+	var board board2048
+	board.addTile(r)
+	board.addTile(r)
+
+	result := Result{}
+	for i, dir := range dirs {
+		gained, moved := board.slide(dir)
+		if !moved {
+			return Result{}, fmt.Errorf("%w: move %d does not change the board", ErrInvalidReplay, i+1)
+		}
+		board.addTile(r)
+
+		result.Score += gained
+		result.Moves++
+	}
+	result.Solved = board.maxTile() >= 2048
+
+	return result, nil
+}
+
+func (game2048) Check(result Result, claimed int, elapsed time.Duration) error {
+	if err := checkPace(result, min2048MoveTime, elapsed); err != nil {
+		return err
+	}
+	if claimed != result.Score {
+		return fmt.Errorf("%w: replay scored %d", ErrReplayMismatch, result.Score)
+	}
+	return nil
+}
+
+// addTile places a new tile on a random empty cell
+func (b *board2048) addTile(r *rng) {
+	var empty [][2]int
+	for row := 0; row < grid2048Size; row++ {
+		for col := 0; col < grid2048Size; col++ {
+			if b[row][col] == 0 {
+				empty = append(empty, [2]int{row, col})
+			}
+		}
+	}
+	if len(empty) == 0 {
+		return
+	}
+
+	cell := empty[r.Intn(len(empty))]
+	value := 2
+	if r.Float() >= 0.9 {
+		value = 4
+	}
+	b[cell[0]][cell[1]] = value
+}
+
+// slide moves every tile as far as it goes in dir, merging equal neighbours
+// once per move, and returns the points gained and whether anything moved
+func (b *board2048) slide(dir direction) (int, bool) {
+	gained, moved := 0, false
+
+	for line := 0; line < grid2048Size; line++ {
+		// Collect the line's cells starting from the edge tiles move towards
+		cells := make([]*int, grid2048Size)
+		for i := 0; i < grid2048Size; i++ {
+			pos := i
+			if dir == right || dir == down {
+				pos = grid2048Size - 1 - i
+			}
+			if dir == left || dir == right {
+				cells[i] = &b[line][pos]
+			} else {
+				cells[i] = &b[pos][line]
+			}
+		}
+
+		var tiles []int
+		for _, cell := range cells {
+			if *cell != 0 {
+				tiles = append(tiles, *cell)
+			}
+		}
+
+		merged := make([]int, 0, grid2048Size)
+		for i := 0; i < len(tiles); i++ {
+			if i+1 < len(tiles) && tiles[i] == tiles[i+1] {
+				merged = append(merged, tiles[i]*2)
+				gained += tiles[i] * 2
+				i++
+			} else {
+				merged = append(merged, tiles[i])
+			}
+		}
+
+		for i, cell := range cells {
+			value := 0
+			if i < len(merged) {
+				value = merged[i]
+			}
+			if *cell != value {
+				moved = true
+			}
+			*cell = value
+		}
+	}
+
+	return gained, moved
+}
+
+// maxTile returns the largest tile on the board
+func (b *board2048) maxTile() int {
+	max := 0
+	for _, row := range b {
+		for _, value := range row {
+			if value > max {
+				max = value
+			}
+		}
+	}
+	return max
+}
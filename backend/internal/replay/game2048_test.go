@@ -0,0 +1,80 @@
+package replay
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGame2048Replay(t *testing.T) {
+	tests := []struct {
+		name      string
+		moves     string
+		wantBoard board2048
+		wantScore int
+		wantErr   error
+	}{
+		{"start", "", board2048{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 2, 0, 0}, {0, 2, 0, 0}}, 0, nil},
+		{"moves", "LURDLURDLURD", board2048{{0, 2, 0, 0}, {0, 0, 0, 2}, {0, 0, 4, 4}, {4, 8, 2, 2}}, 28, nil},
+		{"unchanged board", "UU", board2048{}, 0, ErrInvalidReplay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := game2048{}.Replay(Input{Seed: 42, Moves: tt.moves})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if result.Score != tt.wantScore || result.Moves != len(tt.moves) {
+				t.Errorf("got %+v, want score %d, %d moves", result, tt.wantScore, len(tt.moves))
+			}
+
+			// Play the same moves on a board to compare where the tiles landed
+			r := newRNG(42)
+			var board board2048
+			board.addTile(r)
+			board.addTile(r)
+			dirs, _ := parseDirections(tt.moves)
+			for _, dir := range dirs {
+				board.slide(dir)
+				board.addTile(r)
+			}
+			if board != tt.wantBoard {
+				t.Errorf("board = %v, want %v", board, tt.wantBoard)
+			}
+		})
+	}
+}
+
+func TestGame2048Check(t *testing.T) {
+	result := Result{Score: 28, Moves: 12}
+
+	tests := []struct {
+		name    string
+		claimed int
+		elapsed time.Duration
+		wantErr bool
+	}{
+		{"matches", 28, 2 * time.Second, false},
+		{"different score", 32, 2 * time.Second, true},
+		{"run too short", 28, time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := game2048{}.Check(result, tt.claimed, tt.elapsed)
+			if tt.wantErr && !errors.Is(err, ErrReplayMismatch) {
+				t.Errorf("got %v, want ErrReplayMismatch", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
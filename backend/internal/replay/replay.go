@@ -0,0 +1,149 @@
+// Package replay re-plays recorded puzzle runs server-side so their scores
+// can be proven rather than trusted. Each engine rebuilds the starting
+// position from the run ticket's seed, applies the submitted moves under the
+// game's rules and reports the resulting score and solve state.
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidReplay is returned for move lists that cannot be played, such as
+// unknown moves or moves the rules do not allow
+var ErrInvalidReplay = errors.New("invalid replay")
+
+// ErrReplayMismatch is returned when a replay does not produce the claimed result
+var ErrReplayMismatch = errors.New("replay does not match score")
+
+// Input is a recorded run: the ticket's seed plus everything the player did
+type Input struct {
+	Seed       uint32
+	Difficulty string
+	Moves      string
+}
+
+// Result is the state a replay finished in
+type Result struct {
+	Score  int
+	Moves  int
+	Solved bool
+}
+
+// Engine implements one game's rules
+type Engine interface {
+	// Replay plays the input from the seeded starting position
+	Replay(in Input) (Result, error)
+
+	// Check reports whether a replayed result supports the claimed score.
+	// elapsed is the server-measured length of the run.
+	Check(result Result, claimed int, elapsed time.Duration) error
+}
+
+var engines = map[string]Engine{
+	"game2048":       game2048{},
+	"sliding-puzzle": slidingPuzzle{},
+	"sokoban":        sokoban{},
+	"sudoku":         sudoku{},
+}
+
+// For returns the replay engine for a game, if its scores are replay-verified
+func For(gameID string) (Engine, bool) {
+	engine, ok := engines[gameID]
+	return engine, ok
+}
+
+// maxTimeBonus is the bonus the timed puzzles award for an instant solve.
+// It falls by a point for every whole second taken, as the client's timer
+// counts them.
+const maxTimeBonus = 1000
+
+// timeBonus returns the time bonus for a solve that took d
+func timeBonus(d time.Duration) int {
+	return max(0, maxTimeBonus-int(d/time.Second))
+}
+
+// fastestPlay returns the least time in which moves can be played, taking
+// perMove for each one
+func fastestPlay(moves int, perMove time.Duration) time.Duration {
+	return time.Duration(moves) * perMove
+}
+
+// checkPace rejects a replay whose moves could not have been played within
+// the run
+func checkPace(result Result, perMove, elapsed time.Duration) error {
+	if fastest := fastestPlay(result.Moves, perMove); fastest > elapsed {
+		return fmt.Errorf("%w: %d moves take at least %s", ErrReplayMismatch, result.Moves, fastest)
+	}
+	return nil
+}
+
+// direction is a move on a grid
+type direction struct {
+	dRow, dCol int
+}
+
+var (
+	up    = direction{-1, 0}
+	down  = direction{1, 0}
+	left  = direction{0, -1}
+	right = direction{0, 1}
+)
+
+// parseDirections reads a move list of U, D, L and R characters, ignoring
+// case and whitespace
+func parseDirections(moves string) ([]direction, error) {
+	var dirs []direction
+	for i, ch := range strings.ToUpper(moves) {
+		switch ch {
+		case 'U':
+			dirs = append(dirs, up)
+		case 'D':
+			dirs = append(dirs, down)
+		case 'L':
+			dirs = append(dirs, left)
+		case 'R':
+			dirs = append(dirs, right)
+		case ' ', '\t', '\n', '\r':
+		default:
+			return nil, fmt.Errorf("%w: unknown move %q at offset %d", ErrInvalidReplay, ch, i)
+		}
+	}
+	return dirs, nil
+}
+
+// rng is the mulberry32 generator. It is small enough to reimplement
+// exactly in the browser, so the client and server deal the same game from
+// the same seed. Float matches mulberry32's usual JavaScript form.
+type rng struct {
+	state uint32
+}
+
+// newRNG creates a generator from a seed
+func newRNG(seed uint32) *rng {
+	return &rng{state: seed}
+}
+
+// Float returns a number in [0, 1)
+func (r *rng) Float() float64 {
+	r.state += 0x6D2B79F5
+	t := r.state
+	t = (t ^ (t >> 15)) * (t | 1)
+	t = (t + (t^(t>>7))*(t|61)) ^ t
+	return float64(t^(t>>14)) / 4294967296
+}
+
+// Intn returns an integer in [0, n)
+func (r *rng) Intn(n int) int {
+	return int(r.Float() * float64(n))
+}
+
+// shuffle performs a Fisher-Yates shuffle from the last element down
+func (r *rng) shuffle(values []int) {
+	for i := len(values) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		values[i], values[j] = values[j], values[i]
+	}
+}
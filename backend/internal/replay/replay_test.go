@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// The golden values in these tests come from the JavaScript mulberry32 and
+// the client's dealing code, run under Node
+
+func TestRNGMatchesMulberry32(t *testing.T) {
+	tests := []struct {
+		seed uint32
+		want [3]float64
+	}{
+		{0, [3]float64{0.26642920868471265, 0.0003297457005828619, 0.22327202744781971}},
+		{1, [3]float64{0.62707394058816135, 0.0027357211802154779, 0.52744703995995224}},
+		{42, [3]float64{0.60110375192016363, 0.44829055899754167, 0.85246579349040985}},
+		{123456789, [3]float64{0.25779074383899570, 0.97077211155556142, 0.78532801428809762}},
+		{4294967295, [3]float64{0.89642261411063373, 0.18947825673967600, 0.71565267816185951}},
+	}
+
+	for _, tt := range tests {
+		r := newRNG(tt.seed)
+		for i, want := range tt.want {
+			if got := r.Float(); got != want {
+				t.Errorf("seed %d: value %d = %v, want %v", tt.seed, i, got, want)
+			}
+		}
+	}
+}
+
+func TestParseDirections(t *testing.T) {
+	dirs, err := parseDirections("u d\nLr")
+	if err != nil {
+		t.Fatalf("parseDirections: %v", err)
+	}
+	want := []direction{up, down, left, right}
+	if len(dirs) != len(want) {
+		t.Fatalf("got %d directions, want %d", len(dirs), len(want))
+	}
+	for i := range want {
+		if dirs[i] != want[i] {
+			t.Errorf("direction %d = %v, want %v", i, dirs[i], want[i])
+		}
+	}
+
+	if _, err := parseDirections("UDX"); !errors.Is(err, ErrInvalidReplay) {
+		t.Errorf("unknown move: got %v, want ErrInvalidReplay", err)
+	}
+}
+
+func TestTimeBonus(t *testing.T) {
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 1000},
+		{999 * time.Millisecond, 1000},
+		{90 * time.Second, 910},
+		{1000 * time.Second, 0},
+		{time.Hour, 0},
+	}
+
+	for _, tt := range tests {
+		if got := timeBonus(tt.elapsed); got != tt.want {
+			t.Errorf("timeBonus(%s) = %d, want %d", tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPace(t *testing.T) {
+	tests := []struct {
+		moves   int
+		elapsed time.Duration
+		wantErr bool
+	}{
+		{0, 0, false},
+		{10, time.Second, false},
+		{11, time.Second, true},
+		{1000, time.Second, true},
+	}
+
+	for _, tt := range tests {
+		err := checkPace(Result{Moves: tt.moves}, 100*time.Millisecond, tt.elapsed)
+		if tt.wantErr && !errors.Is(err, ErrReplayMismatch) {
+			t.Errorf("%d moves in %s: got %v, want ErrReplayMismatch", tt.moves, tt.elapsed, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%d moves in %s: unexpected error %v", tt.moves, tt.elapsed, err)
+		}
+	}
+}
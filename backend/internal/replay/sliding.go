@@ -0,0 +1,131 @@
+package replay
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sliding puzzle dimensions and shuffle length
+const (
+	slidingSize    = 4
+	slidingShuffle = 1000
+)
+
+// Sliding puzzle scoring: a base, less a penalty per move, plus the time
+// bonus, but never below the minimum
+const (
+	slidingBaseScore   = 2000
+	slidingMovePenalty = 2
+	slidingMinScore    = 100
+)
+
+// minSlidingMoveTime is the least time a slide takes
+const minSlidingMoveTime = 100 * time.Millisecond
+
+// slidingPuzzle replays the 15-puzzle. The board is shuffled from the solved
+// position by 1000 random slides of the empty cell, each choosing among its
+// in-bounds neighbours in up, down, left, right order. A move names the
+// direction a tile slides into the empty cell, as the arrow keys do. The run
+// must end solved, and scores 2000 less 2 per move before the solving one,
+// plus the time bonus, but never below 100. The solve time cannot be
+// replayed, so the claimed score must be one that a time within the run
+// gives, and no faster than the moves can be played.
+type slidingPuzzle struct{}
+
+type slidingBoard struct {
+	cells              [slidingSize][slidingSize]int
+	emptyRow, emptyCol int
+}
+
+func (slidingPuzzle) Replay(in Input) (Result, error) {
+	dirs, err := parseDirections(in.Moves)
+	if err != nil {
+		return Result{}, err
+	}
+
+	board := newSlidingBoard(newRNG(in.Seed))
+
+	result := Result{}
+	for i, dir := range dirs {
+		if board.solved() {
+			return Result{}, fmt.Errorf("%w: move %d made after the puzzle was solved", ErrInvalidReplay, i+1)
+		}
+
+		// The tile moving in dir sits on the opposite side of the empty cell
+		row, col := board.emptyRow-dir.dRow, board.emptyCol-dir.dCol
+		if row < 0 || row >= slidingSize || col < 0 || col >= slidingSize {
+			return Result{}, fmt.Errorf("%w: move %d has no tile to slide", ErrInvalidReplay, i+1)
+		}
+		board.swapEmpty(row, col)
+		result.Moves++
+	}
+
+	// The score before the time bonus; the client does not count the solving move
+	result.Score = slidingBaseScore - slidingMovePenalty*max(0, result.Moves-1)
+	result.Solved = board.solved()
+
+	return result, nil
+}
+
+func (slidingPuzzle) Check(result Result, claimed int, elapsed time.Duration) error {
+	if !result.Solved {
+		return fmt.Errorf("%w: puzzle is not solved", ErrReplayMismatch)
+	}
+	if err := checkPace(result, minSlidingMoveTime, elapsed); err != nil {
+		return err
+	}
+	best := max(slidingMinScore, result.Score+timeBonus(fastestPlay(result.Moves, minSlidingMoveTime)))
+	worst := max(slidingMinScore, result.Score+timeBonus(elapsed))
+	if claimed < worst || claimed > best {
+		return fmt.Errorf("%w: replay scores %d to %d within the run", ErrReplayMismatch, worst, best)
+	}
+	return nil
+}
+
+// newSlidingBoard deals a shuffled board
+func newSlidingBoard(r *rng) *slidingBoard {
+	board := &slidingBoard{emptyRow: slidingSize - 1, emptyCol: slidingSize - 1}
+	for row := 0; row < slidingSize; row++ {
+		for col := 0; col < slidingSize; col++ {
+			board.cells[row][col] = row*slidingSize + col + 1
+		}
+	}
+	board.cells[slidingSize-1][slidingSize-1] = 0
+
+	for i := 0; i < slidingShuffle; i++ {
+		var options [][2]int
+		for _, dir := range []direction{up, down, left, right} {
+			row, col := board.emptyRow+dir.dRow, board.emptyCol+dir.dCol
+			if row >= 0 && row < slidingSize && col >= 0 && col < slidingSize {
+				options = append(options, [2]int{row, col})
+			}
+		}
+		next := options[r.Intn(len(options))]
+		board.swapEmpty(next[0], next[1])
+	}
+
+	return board
+}
+
+// swapEmpty moves the tile at row, col into the empty cell
+func (b *slidingBoard) swapEmpty(row, col int) {
+	b.cells[b.emptyRow][b.emptyCol] = b.cells[row][col]
+	b.cells[row][col] = 0
+	b.emptyRow, b.emptyCol = row, col
+}
+
+// solved reports whether the tiles are in order with the empty cell last
+func (b *slidingBoard) solved() bool {
+	for row := 0; row < slidingSize; row++ {
+		for col := 0; col < slidingSize; col++ {
+			expected := row*slidingSize + col + 1
+			if row == slidingSize-1 && col == slidingSize-1 {
+				expected = 0
+			}
+			if b.cells[row][col] != expected {
+				return false
+			}
+		}
+	}
+	return true
+}
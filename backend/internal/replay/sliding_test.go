@@ -0,0 +1,98 @@
+package replay
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// sliding42Solve undoes the shuffle dealt from seed 42, with slides that
+// cancel out removed
+const sliding42Solve = "LUURURRDLLULDRDLDRRUUURDLURDDDLLURDRUUULDLLURDLURRRDLURDDDLLUURURDDDLLULDRULURRDDLLURDRULDRURULURDDDLLULDRRUURDLLLURURDLULDRURDRDLLUURRDDLURULDLURDLLDDRUUURDLLURRRDLDLURURDLDLDRURULURDDLLURRULDRULLLDRRURDLLDDRRULLLURULDRULDDRULURDDRRULDLLURRDLURDRULLLURDLURDDRRDLULDRURDLLUUULDRRRDLLUURDRULDDLLURRDLLURDDRRULUULDRULDDRDRULUULDRULL"
+
+func TestSlidingShuffle(t *testing.T) {
+	tests := []struct {
+		seed     uint32
+		want     [slidingSize][slidingSize]int
+		row, col int
+	}{
+		{1, [slidingSize][slidingSize]int{{13, 11, 9, 3}, {14, 1, 8, 0}, {6, 7, 5, 4}, {15, 2, 10, 12}}, 1, 3},
+		{42, [slidingSize][slidingSize]int{{1, 6, 0, 9}, {3, 7, 15, 13}, {5, 14, 10, 11}, {8, 12, 4, 2}}, 0, 2},
+	}
+
+	for _, tt := range tests {
+		board := newSlidingBoard(newRNG(tt.seed))
+		if board.cells != tt.want {
+			t.Errorf("seed %d: board = %v, want %v", tt.seed, board.cells, tt.want)
+		}
+		if board.emptyRow != tt.row || board.emptyCol != tt.col {
+			t.Errorf("seed %d: empty cell at %d,%d, want %d,%d", tt.seed, board.emptyRow, board.emptyCol, tt.row, tt.col)
+		}
+	}
+}
+
+func TestSlidingReplay(t *testing.T) {
+	tests := []struct {
+		name       string
+		moves      string
+		wantScore  int
+		wantSolved bool
+		wantErr    error
+	}{
+		{"solved", sliding42Solve, 2000 - 2*(len(sliding42Solve)-1), true, nil},
+		{"unsolved", "LR", 1998, false, nil},
+		{"no tile", "D", 0, false, ErrInvalidReplay},
+		{"after solving", sliding42Solve + "D", 0, false, ErrInvalidReplay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := slidingPuzzle{}.Replay(Input{Seed: 42, Moves: tt.moves})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if result.Score != tt.wantScore || result.Solved != tt.wantSolved || result.Moves != len(tt.moves) {
+				t.Errorf("got %+v, want score %d, solved %v, %d moves", result, tt.wantScore, tt.wantSolved, len(tt.moves))
+			}
+		})
+	}
+}
+
+func TestSlidingCheck(t *testing.T) {
+	// 330 slides take at least 33s, so the bonus is at most 967
+	solved := Result{Score: 1342, Moves: 330, Solved: true}
+
+	tests := []struct {
+		name    string
+		result  Result
+		claimed int
+		elapsed time.Duration
+		wantErr bool
+	}{
+		{"fastest", solved, 1342 + 967, time.Minute, false},
+		{"slowest", solved, 1342 + 940, time.Minute, false},
+		{"faster than moves allow", solved, 1342 + 968, time.Minute, true},
+		{"slower than the run", solved, 1342 + 939, time.Minute, true},
+		{"run too short", solved, 1342 + 970, 30 * time.Second, true},
+		{"minimum score", Result{Score: -500, Moves: 1251, Solved: true}, 100, time.Hour, false},
+		{"unsolved", Result{Score: 1342, Moves: 330}, 1342 + 950, time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := slidingPuzzle{}.Check(tt.result, tt.claimed, tt.elapsed)
+			if tt.wantErr && !errors.Is(err, ErrReplayMismatch) {
+				t.Errorf("got %v, want ErrReplayMismatch", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
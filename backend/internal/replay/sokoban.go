@@ -0,0 +1,147 @@
+package replay
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sokoban cell types, as used by the client's level data
+const (
+	sokobanFloor = iota
+	sokobanWall
+	sokobanTarget
+	sokobanBox
+	sokobanPlayer
+	sokobanBoxOnTarget
+	sokobanPlayerOnTarget
+)
+
+// minSokobanMoveTime is the least time a Sokoban step takes
+const minSokobanMoveTime = 100 * time.Millisecond
+
+// sokobanLevel is the level the client ships with
+var sokobanLevel = [][]int{
+	{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 1, 1, 1, 0, 0, 0, 1, 1, 1, 1, 0, 0, 1},
+	{1, 0, 1, 2, 2, 0, 0, 0, 0, 0, 0, 1, 0, 0, 1},
+	{1, 0, 1, 2, 2, 0, 0, 3, 3, 0, 0, 1, 0, 0, 1},
+	{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 1},
+	{1, 0, 1, 1, 1, 1, 0, 0, 0, 1, 1, 1, 0, 0, 1},
+	{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+}
+
+// sokoban replays the Sokoban level. The level is fixed, so the seed is not
+// used. Each move walks the player one cell, pushing a box if one is in the
+// way; walking into a wall or pushing a box into a wall or another box is
+// not allowed. The run must end with every box on a target, with the moves
+// fitting within the run, and scores 1000 less 10 per move and 5 per push,
+// but never below 100.
+type sokoban struct{}
+
+func (sokoban) Replay(in Input) (Result, error) {
+	dirs, err := parseDirections(in.Moves)
+	if err != nil {
+		return Result{}, err
+	}
+
+	board := make([][]int, len(sokobanLevel))
+	var playerRow, playerCol int
+	for row := range sokobanLevel {
+		board[row] = append([]int(nil), sokobanLevel[row]...)
+		for col, cell := range board[row] {
+			if cell == sokobanPlayer || cell == sokobanPlayerOnTarget {
+				playerRow, playerCol = row, col
+			}
+		}
+	}
+
+	moves, pushes := 0, 0
+	for i, dir := range dirs {
+		if sokobanSolved(board) {
+			return Result{}, fmt.Errorf("%w: move %d made after the level was solved", ErrInvalidReplay, i+1)
+		}
+
+		row, col := playerRow+dir.dRow, playerCol+dir.dCol
+		if !sokobanOpen(board, row, col) {
+			return Result{}, fmt.Errorf("%w: move %d walks into a wall", ErrInvalidReplay, i+1)
+		}
+
+		if cell := board[row][col]; cell == sokobanBox || cell == sokobanBoxOnTarget {
+			boxRow, boxCol := row+dir.dRow, col+dir.dCol
+			if !sokobanOpen(board, boxRow, boxCol) || board[boxRow][boxCol] == sokobanBox || board[boxRow][boxCol] == sokobanBoxOnTarget {
+				return Result{}, fmt.Errorf("%w: move %d pushes a box that cannot move", ErrInvalidReplay, i+1)
+			}
+
+			if board[boxRow][boxCol] == sokobanTarget {
+				board[boxRow][boxCol] = sokobanBoxOnTarget
+			} else {
+				board[boxRow][boxCol] = sokobanBox
+			}
+			if cell == sokobanBoxOnTarget {
+				board[row][col] = sokobanTarget
+			} else {
+				board[row][col] = sokobanFloor
+			}
+			pushes++
+		}
+
+		if board[playerRow][playerCol] == sokobanPlayerOnTarget {
+			board[playerRow][playerCol] = sokobanTarget
+		} else {
+			board[playerRow][playerCol] = sokobanFloor
+		}
+		if board[row][col] == sokobanTarget {
+			board[row][col] = sokobanPlayerOnTarget
+		} else {
+			board[row][col] = sokobanPlayer
+		}
+		playerRow, playerCol = row, col
+		moves++
+	}
+
+	score := 1000 - moves*10 - pushes*5
+	if score < 100 {
+		score = 100
+	}
+
+	return Result{Score: score, Moves: moves, Solved: sokobanSolved(board)}, nil
+}
+
+func (sokoban) Check(result Result, claimed int, elapsed time.Duration) error {
+	if !result.Solved {
+		return fmt.Errorf("%w: level is not solved", ErrReplayMismatch)
+	}
+	if err := checkPace(result, minSokobanMoveTime, elapsed); err != nil {
+		return err
+	}
+	if claimed != result.Score {
+		return fmt.Errorf("%w: replay scored %d", ErrReplayMismatch, result.Score)
+	}
+	return nil
+}
+
+// sokobanOpen reports whether a cell is on the board and not a wall
+func sokobanOpen(board [][]int, row, col int) bool {
+	return row >= 0 && row < len(board) && col >= 0 && col < len(board[row]) && board[row][col] != sokobanWall
+}
+
+// sokobanSolved reports whether every box is on a target
+func sokobanSolved(board [][]int) bool {
+	placed := false
+	for _, row := range board {
+		for _, cell := range row {
+			if cell == sokobanBox {
+				return false
+			}
+			if cell == sokobanBoxOnTarget {
+				placed = true
+			}
+		}
+	}
+	return placed
+}
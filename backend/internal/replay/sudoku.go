@@ -0,0 +1,200 @@
+package replay
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sudoku dimensions
+const (
+	sudokuSize = 9
+	sudokuBox  = 3
+)
+
+// Sudoku scoring: points per placement that clashes with nothing, points
+// lost per one that does, and the penalty per mistake applied on solving
+const (
+	sudokuPlacementPoints = 10
+	sudokuMistakePoints   = 5
+	sudokuMistakePenalty  = 50
+)
+
+// minSudokuMoveTime is the least time a placement takes: choosing a cell,
+// then a digit
+const minSudokuMoveTime = 500 * time.Millisecond
+
+// sudokuRemoved is the number of cells cleared from the solution per difficulty
+var sudokuRemoved = map[string]int{
+	"easy":   40,
+	"medium": 50,
+	"hard":   60,
+}
+
+// sudoku replays Sudoku. A full grid is generated by backtracking over the
+// cells in row-major order, trying the digits of each cell in a freshly
+// shuffled order on every visit; cells are then cleared at random (40, 50 or
+// 60 for easy, medium or hard; medium by default), redrawing any cell that
+// is already empty. Moves are whitespace-separated "rcv" triples placing
+// value v (0 clears) at row r, column c, counted from 1; givens cannot be
+// changed. A placement that clashes with nothing earns 10 points; one that
+// repeats a digit in its row, column or box is a mistake and loses 5, but
+// never takes the points below 0. The run must end solved, and scores the
+// points before the solving placement, less 50 per mistake, plus the time
+// bonus. The solve time cannot be replayed, so the claimed score must be one
+// that a time within the run gives, and no faster than the moves can be
+// played.
+type sudoku struct{}
+
+type sudokuGrid [sudokuSize][sudokuSize]int
+
+func (sudoku) Replay(in Input) (Result, error) {
+	difficulty := in.Difficulty
+	if difficulty == "" {
+		difficulty = "medium"
+	}
+	removed, ok := sudokuRemoved[difficulty]
+	if !ok {
+		return Result{}, fmt.Errorf("%w: unknown difficulty %q", ErrInvalidReplay, difficulty)
+	}
+
+	r := newRNG(in.Seed)
+	var solution sudokuGrid
+	solution.fill(r)
+
+	puzzle := solution
+	for i := 0; i < removed; i++ {
+		row, col := r.Intn(sudokuSize), r.Intn(sudokuSize)
+		for puzzle[row][col] == 0 {
+			row, col = r.Intn(sudokuSize), r.Intn(sudokuSize)
+		}
+		puzzle[row][col] = 0
+	}
+
+	grid := puzzle
+	result := Result{}
+	points, mistakes := 0, 0
+	for i, move := range strings.Fields(in.Moves) {
+		if grid.solved() {
+			return Result{}, fmt.Errorf("%w: move %d made after the puzzle was solved", ErrInvalidReplay, i+1)
+		}
+
+		if len(move) != 3 || move[0] < '1' || move[0] > '9' || move[1] < '1' || move[1] > '9' || move[2] < '0' || move[2] > '9' {
+			return Result{}, fmt.Errorf("%w: malformed move %q", ErrInvalidReplay, move)
+		}
+		row, col, value := int(move[0]-'1'), int(move[1]-'1'), int(move[2]-'0')
+		if puzzle[row][col] != 0 {
+			return Result{}, fmt.Errorf("%w: move %d changes a given", ErrInvalidReplay, i+1)
+		}
+
+		grid[row][col] = value
+		result.Moves++
+
+		// The client scores on solving with the points it had before the solving placement
+		result.Score = points - sudokuMistakePenalty*mistakes
+		switch {
+		case value == 0:
+		case grid.clashes(row, col):
+			mistakes++
+			points = max(0, points-sudokuMistakePoints)
+		default:
+			points += sudokuPlacementPoints
+		}
+	}
+	result.Solved = grid.solved()
+
+	return result, nil
+}
+
+func (sudoku) Check(result Result, claimed int, elapsed time.Duration) error {
+	if !result.Solved {
+		return fmt.Errorf("%w: puzzle is not solved", ErrReplayMismatch)
+	}
+	if err := checkPace(result, minSudokuMoveTime, elapsed); err != nil {
+		return err
+	}
+	best := result.Score + timeBonus(fastestPlay(result.Moves, minSudokuMoveTime))
+	worst := result.Score + timeBonus(elapsed)
+	if claimed < worst || claimed > best {
+		return fmt.Errorf("%w: replay scores %d to %d within the run", ErrReplayMismatch, worst, best)
+	}
+	return nil
+}
+
+// fill completes the grid by backtracking, returning false if it cannot
+func (g *sudokuGrid) fill(r *rng) bool {
+	for row := 0; row < sudokuSize; row++ {
+		for col := 0; col < sudokuSize; col++ {
+			if g[row][col] != 0 {
+				continue
+			}
+
+			digits := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+			r.shuffle(digits)
+			for _, digit := range digits {
+				if g.allows(row, col, digit) {
+					g[row][col] = digit
+					if g.fill(r) {
+						return true
+					}
+					g[row][col] = 0
+				}
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// allows reports whether digit appears nowhere in the cell's row, column or box
+func (g *sudokuGrid) allows(row, col, digit int) bool {
+	for i := 0; i < sudokuSize; i++ {
+		if g[row][i] == digit || g[i][col] == digit {
+			return false
+		}
+	}
+
+	boxRow, boxCol := row/sudokuBox*sudokuBox, col/sudokuBox*sudokuBox
+	for i := 0; i < sudokuBox; i++ {
+		for j := 0; j < sudokuBox; j++ {
+			if g[boxRow+i][boxCol+j] == digit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// clashes reports whether the cell's digit appears again in its row, column
+// or box
+func (g *sudokuGrid) clashes(row, col int) bool {
+	digit := g[row][col]
+	g[row][col] = 0
+	defer func() { g[row][col] = digit }()
+	return !g.allows(row, col, digit)
+}
+
+// solved reports whether every row, column and box holds each digit once
+func (g *sudokuGrid) solved() bool {
+	for i := 0; i < sudokuSize; i++ {
+		var rowSeen, colSeen, boxSeen [sudokuSize + 1]bool
+		for j := 0; j < sudokuSize; j++ {
+			boxRow := i/sudokuBox*sudokuBox + j/sudokuBox
+			boxCol := i%sudokuBox*sudokuBox + j%sudokuBox
+			for _, cell := range []struct {
+				value int
+				seen  *[sudokuSize + 1]bool
+			}{
+				{g[i][j], &rowSeen},
+				{g[j][i], &colSeen},
+				{g[boxRow][boxCol], &boxSeen},
+			} {
+				if cell.value == 0 || cell.seen[cell.value] {
+					return false
+				}
+				cell.seen[cell.value] = true
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sudoku42 is the medium puzzle dealt from seed 42 and its solution, row by row
+const (
+	sudoku42Puzzle   = "000700050000000400020000030301507000502800070008691020000900745149075602000000310"
+	sudoku42Solution = "836749251915283467724156938361527894592834176478691523683912745149375682257468319"
+)
+
+// sudokuMoves returns the moves filling every empty cell of puzzle from
+// solution, in row-major order
+func sudokuMoves(puzzle, solution string) []string {
+	var moves []string
+	for i := range puzzle {
+		if puzzle[i] == '0' {
+			moves = append(moves, fmt.Sprintf("%d%d%c", i/sudokuSize+1, i%sudokuSize+1, solution[i]))
+		}
+	}
+	return moves
+}
+
+func TestSudokuDeal(t *testing.T) {
+	tests := []struct {
+		seed             uint32
+		solution, puzzle string
+	}{
+		{1,
+			"876951324325684791491237586534712869217869453689345172953428617142576938768193245",
+			"806050004000080701000000080034000860000000400609045100050408607100070008068003205"},
+		{42, sudoku42Solution, sudoku42Puzzle},
+	}
+
+	for _, tt := range tests {
+		r := newRNG(tt.seed)
+		var grid sudokuGrid
+		if !grid.fill(r) {
+			t.Fatalf("seed %d: fill failed", tt.seed)
+		}
+		if got := sudokuString(grid); got != tt.solution {
+			t.Errorf("seed %d: solution = %s, want %s", tt.seed, got, tt.solution)
+		}
+
+		// Replaying no moves leaves the puzzle as dealt, which is unsolved
+		result, err := sudoku{}.Replay(Input{Seed: tt.seed, Difficulty: "medium"})
+		if err != nil || result.Solved {
+			t.Errorf("seed %d: empty replay = %+v, %v", tt.seed, result, err)
+		}
+		if blanks := strings.Count(tt.puzzle, "0"); blanks != sudokuRemoved["medium"] {
+			t.Errorf("seed %d: %d cells cleared, want %d", tt.seed, blanks, sudokuRemoved["medium"])
+		}
+	}
+}
+
+func TestSudokuReplay(t *testing.T) {
+	solve := sudokuMoves(sudoku42Puzzle, sudoku42Solution)
+	blanks := len(solve)
+
+	tests := []struct {
+		name       string
+		moves      []string
+		wantScore  int
+		wantSolved bool
+		wantErr    error
+	}{
+		{"solved", solve, 10 * (blanks - 1), true, nil},
+		// 7 is already in the first row, so placing it is a mistake that costs
+		// 5 points, which cannot go below 0, and 50 on solving
+		{"mistake", append([]string{"117"}, solve...), 10*(blanks-1) - 50, true, nil},
+		{"cleared", append([]string{"118", "110"}, solve...), 10*(blanks-1) + 10, true, nil},
+		{"unsolved", solve[:blanks-1], 10 * (blanks - 2), false, nil},
+		{"given", []string{"145"}, 0, false, ErrInvalidReplay},
+		{"malformed", []string{"1a5"}, 0, false, ErrInvalidReplay},
+		{"after solving", append(solve, "118"), 0, false, ErrInvalidReplay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := sudoku{}.Replay(Input{Seed: 42, Moves: strings.Join(tt.moves, " ")})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if result.Score != tt.wantScore || result.Solved != tt.wantSolved || result.Moves != len(tt.moves) {
+				t.Errorf("got %+v, want score %d, solved %v, %d moves", result, tt.wantScore, tt.wantSolved, len(tt.moves))
+			}
+		})
+	}
+}
+
+func TestSudokuCheck(t *testing.T) {
+	// 50 placements take at least 25s, so the bonus is at most 975
+	solved := Result{Score: 490, Moves: 50, Solved: true}
+
+	tests := []struct {
+		name    string
+		result  Result
+		claimed int
+		elapsed time.Duration
+		wantErr bool
+	}{
+		{"fastest", solved, 490 + 975, 10 * time.Minute, false},
+		{"slowest", solved, 490 + 400, 10 * time.Minute, false},
+		{"bonus spent", solved, 490, time.Hour, false},
+		{"faster than moves allow", solved, 490 + 976, 10 * time.Minute, true},
+		{"slower than the run", solved, 490 + 399, 10 * time.Minute, true},
+		{"run too short", solved, 490 + 980, 20 * time.Second, true},
+		{"unsolved", Result{Score: 490, Moves: 50}, 490 + 500, 10 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sudoku{}.Check(tt.result, tt.claimed, tt.elapsed)
+			if tt.wantErr && !errors.Is(err, ErrReplayMismatch) {
+				t.Errorf("got %v, want ErrReplayMismatch", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+// sudokuString writes a grid row by row
+func sudokuString(g sudokuGrid) string {
+	var b strings.Builder
+	for _, row := range g {
+		for _, value := range row {
+			b.WriteByte(byte('0' + value))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUnknownAchievement is returned for achievement IDs that do not exist
+var ErrUnknownAchievement = errors.New("unknown achievement")
+
+// AchievementService unlocks achievements for players whose stats meet
+// their rules, as scores are submitted and, for new rules, from history
+type AchievementService struct {
+	db    *pgxpool.Pool
+	games *gameCatalog
+}
+
+// NewAchievementService creates a new achievement service
+func NewAchievementService(db *pgxpool.Pool) *AchievementService {
+	return &AchievementService{
+		db:    db,
+		games: newGameCatalog(db),
+	}
+}
+
+// gameProgress is a player's standing in one game. bestRank is 0 if the
+// player has never been ranked.
+type gameProgress struct {
+	plays     int
+	bestScore int
+	bestRank  int
+}
+
+// playerProgress is the part of a player's stats achievement rules measure
+type playerProgress struct {
+	games         map[string]gameProgress
+	longestStreak int
+}
+
+// achievementMet reports whether progress meets an achievement's rule over
+// the enabled games in games
+func achievementMet(a models.Achievement, p playerProgress, games []models.Game) bool {
+	if a.Kind == models.AchievementStreak {
+		return p.longestStreak >= a.Threshold
+	}
+
+	measured, plays := 0, 0
+	for _, game := range games {
+		if (a.GameID != "" && game.ID != a.GameID) || (a.Category != "" && game.Category != a.Category) {
+			continue
+		}
+		measured++
+
+		progress, played := p.games[game.ID]
+		switch a.Kind {
+		case models.AchievementScore:
+			sign := game.Scoring.Direction.Sign()
+			if played && progress.bestScore*sign >= a.Threshold*sign {
+				return true
+			}
+		case models.AchievementRank:
+			if progress.bestRank > 0 && progress.bestRank <= a.Threshold {
+				return true
+			}
+		case models.AchievementPlays:
+			plays += progress.plays
+		case models.AchievementPlayAll:
+			if progress.plays < a.Threshold || !played {
+				return false
+			}
+		}
+	}
+
+	switch a.Kind {
+	case models.AchievementPlays:
+		return plays >= a.Threshold
+	case models.AchievementPlayAll:
+		return measured > 0
+	}
+	return false
+}
+
+// GetAchievements returns the achievement catalogue, oldest first
+func (s *AchievementService) GetAchievements(ctx context.Context) (*models.AchievementsResponse, error) {
+	achievements, err := s.catalog(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AchievementsResponse{
+		Achievements: achievements,
+		Total:        len(achievements),
+	}, nil
+}
+
+// GetAchievement returns one achievement from the catalogue
+func (s *AchievementService) GetAchievement(ctx context.Context, achievementID string) (*models.Achievement, error) {
+	achievements, err := s.catalog(ctx, achievementID)
+	if err != nil {
+		return nil, err
+	}
+	if len(achievements) == 0 {
+		return nil, ErrUnknownAchievement
+	}
+	return &achievements[0], nil
+}
+
+// GetPlayerAchievements returns every achievement with when the player
+// unlocked it, most recent unlocks first and locked achievements last
+func (s *AchievementService) GetPlayerAchievements(ctx context.Context, playerID uuid.UUID) (*models.PlayerAchievementsResponse, error) {
+	achievements, err := s.catalog(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT achievement_id, unlocked_at
+		FROM player_achievements
+		WHERE player_id = $1
+	`, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player achievements: %w", err)
+	}
+	defer rows.Close()
+
+	unlocked := make(map[string]time.Time)
+	for rows.Next() {
+		var achievementID string
+		var unlockedAt time.Time
+		if err := rows.Scan(&achievementID, &unlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan player achievement: %w", err)
+		}
+		unlocked[achievementID] = unlockedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get player achievements: %w", err)
+	}
+
+	response := &models.PlayerAchievementsResponse{
+		Achievements: make([]models.PlayerAchievement, len(achievements)),
+		Total:        len(achievements),
+	}
+	for i, achievement := range achievements {
+		response.Achievements[i].Achievement = achievement
+		if unlockedAt, ok := unlocked[achievement.ID]; ok {
+			response.Achievements[i].UnlockedAt = &unlockedAt
+			response.Unlocked++
+		}
+	}
+	sort.SliceStable(response.Achievements, func(i, j int) bool {
+		a, b := response.Achievements[i].UnlockedAt, response.Achievements[j].UnlockedAt
+		return a != nil && (b == nil || a.After(*b))
+	})
+
+	return response, nil
+}
+
+// UnlockEarned unlocks every achievement a player's stats now meet,
+// crediting scoreID with them, and returns the new unlocks. It runs once a
+// score's stats are committed and its rank recorded.
+func (s *AchievementService) UnlockEarned(ctx context.Context, playerID, scoreID uuid.UUID) ([]models.AchievementUnlock, error) {
+	locked, err := s.rules(ctx, `
+		SELECT id, name, description, kind, COALESCE(game_id, ''), COALESCE(category, ''), threshold
+		FROM achievements a
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM player_achievements pa WHERE pa.player_id = $1 AND pa.achievement_id = a.id
+		)
+	`, playerID)
+	if err != nil || len(locked) == 0 {
+		return nil, err
+	}
+
+	games, err := s.games.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT s.game_id, s.plays, s.best_score, COALESCE(s.best_rank, 0), ps.longest_streak
+		FROM player_game_stats s
+		JOIN player_stats ps ON ps.player_id = s.player_id
+		WHERE s.player_id = $1
+	`
+
+	rows, err := s.db.Query(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := playerProgress{games: make(map[string]gameProgress)}
+	for rows.Next() {
+		var gameID string
+		var game gameProgress
+		if err := rows.Scan(&gameID, &game.plays, &game.bestScore, &game.bestRank, &progress.longestStreak); err != nil {
+			return nil, fmt.Errorf("failed to scan player progress: %w", err)
+		}
+		progress.games[gameID] = game
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get player progress: %w", err)
+	}
+
+	earned := make(map[string]models.Achievement)
+	var earnedIDs []string
+	for _, achievement := range locked {
+		if achievementMet(achievement, progress, games) {
+			earned[achievement.ID] = achievement
+			earnedIDs = append(earnedIDs, achievement.ID)
+		}
+	}
+	if len(earnedIDs) == 0 {
+		return nil, nil
+	}
+
+	// Record the unlocks; a concurrent submission may have recorded some first
+	query = `
+		INSERT INTO player_achievements (player_id, achievement_id, score_id)
+		SELECT $1, id, $3 FROM UNNEST($2::text[]) AS id
+		ON CONFLICT (player_id, achievement_id) DO NOTHING
+		RETURNING achievement_id, unlocked_at
+	`
+
+	rows, err = s.db.Query(ctx, query, playerID, earnedIDs, scoreID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock achievements: %w", err)
+	}
+	defer rows.Close()
+
+	var unlocks []models.AchievementUnlock
+	for rows.Next() {
+		var unlock models.AchievementUnlock
+		if err := rows.Scan(&unlock.ID, &unlock.UnlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan unlocked achievement: %w", err)
+		}
+		unlock.Name, unlock.Description = earned[unlock.ID].Name, earned[unlock.ID].Description
+		unlocks = append(unlocks, unlock)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to unlock achievements: %w", err)
+	}
+
+	return unlocks, nil
+}
+
+// Backfill unlocks the named achievements for every player whose history
+// already meets them, and returns how many players each was unlocked for.
+// With no IDs it backfills the achievements that have never been
+// backfilled, as a newly added rule has not. A player's best rank in a game
+// is the better of the one recorded and their rank by best score now.
+func (s *AchievementService) Backfill(ctx context.Context, achievementIDs []string) (map[string]int, error) {
+	// Name each achievement once, so a repeated ID is not mistaken for a missing one
+	requested := make(map[string]bool)
+	ids := []string{}
+	for _, id := range achievementIDs {
+		if !requested[id] {
+			requested[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	achievements, err := s.rules(ctx, `
+		SELECT id, name, description, kind, COALESCE(game_id, ''), COALESCE(category, ''), threshold
+		FROM achievements
+		WHERE (CARDINALITY($1::text[]) = 0 AND backfilled_at IS NULL) OR id = ANY($1::text[])
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, achievement := range achievements {
+		delete(requested, achievement.ID)
+	}
+	for _, id := range ids {
+		if requested[id] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAchievement, id)
+		}
+	}
+	if len(achievements) == 0 {
+		return map[string]int{}, nil
+	}
+
+	games, err := s.games.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT s.player_id, s.game_id, s.plays, s.best_score,
+		       COALESCE(LEAST(s.best_rank, RANK() OVER (
+		           PARTITION BY s.game_id
+		           ORDER BY s.best_score * CASE WHEN g.score_direction = 'lower' THEN -1 ELSE 1 END DESC
+		       )), 0)::int,
+		       COALESCE(ps.longest_streak, 0)
+		FROM player_game_stats s
+		JOIN games g ON g.id = s.game_id
+		LEFT JOIN player_stats ps ON ps.player_id = s.player_id
+		ORDER BY s.player_id
+	`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player progress: %w", err)
+	}
+	defer rows.Close()
+
+	// Rows arrive grouped by player; measure each player once all of theirs are read
+	earned := make(map[string][]uuid.UUID)
+	var current uuid.UUID
+	var progress playerProgress
+	measure := func() {
+		for _, achievement := range achievements {
+			if progress.games != nil && achievementMet(achievement, progress, games) {
+				earned[achievement.ID] = append(earned[achievement.ID], current)
+			}
+		}
+	}
+	for rows.Next() {
+		var playerID uuid.UUID
+		var gameID string
+		var game gameProgress
+		var longestStreak int
+		if err := rows.Scan(&playerID, &gameID, &game.plays, &game.bestScore, &game.bestRank, &longestStreak); err != nil {
+			return nil, fmt.Errorf("failed to scan player progress: %w", err)
+		}
+		if playerID != current {
+			measure()
+			current = playerID
+			progress = playerProgress{games: make(map[string]gameProgress), longestStreak: longestStreak}
+		}
+		progress.games[gameID] = game
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get player progress: %w", err)
+	}
+	measure()
+
+	counts := make(map[string]int)
+	for _, achievement := range achievements {
+		count, err := s.backfillOne(ctx, achievement.ID, earned[achievement.ID])
+		if err != nil {
+			return nil, err
+		}
+		counts[achievement.ID] = count
+	}
+
+	return counts, nil
+}
+
+// backfillOne unlocks an achievement for the players who earned it and marks
+// it backfilled, in one transaction. Players who already hold it are left as
+// they are, so backfilling again unlocks nothing twice.
+func (s *AchievementService) backfillOne(ctx context.Context, achievementID string, playerIDs []uuid.UUID) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill %s: %w", achievementID, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var count int
+	if len(playerIDs) > 0 {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO player_achievements (player_id, achievement_id)
+			SELECT player_id, $2::text FROM UNNEST($1::uuid[]) AS player_id
+			ON CONFLICT (player_id, achievement_id) DO NOTHING
+		`, playerIDs, achievementID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to backfill %s: %w", achievementID, err)
+		}
+		count = int(tag.RowsAffected())
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE achievements SET backfilled_at = LOCALTIMESTAMP WHERE id = $1`, achievementID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill %s: %w", achievementID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to backfill %s: %w", achievementID, err)
+	}
+	return count, nil
+}
+
+// BackfillNew backfills the achievements added since the last backfill,
+// logging what it unlocked
+func (s *AchievementService) BackfillNew(ctx context.Context) {
+	counts, err := s.Backfill(ctx, nil)
+	if err != nil {
+		log.Printf("Failed to backfill achievements: %v", err)
+		return
+	}
+	for achievementID, count := range counts {
+		log.Printf("Backfilled achievement %s for %d players", achievementID, count)
+	}
+}
+
+// catalog reads one achievement, or every achievement if achievementID is
+// empty, with how many players have unlocked it
+func (s *AchievementService) catalog(ctx context.Context, achievementID string) ([]models.Achievement, error) {
+	query := `
+		SELECT a.id, a.name, a.description, a.kind, COALESCE(a.game_id, ''), COALESCE(a.category, ''), a.threshold,
+		       COUNT(pa.player_id), (SELECT COUNT(*) FROM player_stats)
+		FROM achievements a
+		LEFT JOIN player_achievements pa ON pa.achievement_id = a.id
+		WHERE $1 = '' OR a.id = $1
+		GROUP BY a.id
+		ORDER BY a.created_at, a.id
+	`
+
+	rows, err := s.db.Query(ctx, query, achievementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	defer rows.Close()
+
+	achievements := []models.Achievement{}
+	for rows.Next() {
+		var a models.Achievement
+		var players int
+		err := rows.Scan(&a.ID, &a.Name, &a.Description, &a.Kind, &a.GameID, &a.Category, &a.Threshold,
+			&a.UnlockedBy, &players)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan achievement: %w", err)
+		}
+		if players > 0 {
+			a.UnlockedPercent = math.Round(float64(a.UnlockedBy)/float64(players)*1000) / 10
+		}
+		achievements = append(achievements, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+
+	return achievements, nil
+}
+
+// rules reads the achievements selected by query
+func (s *AchievementService) rules(ctx context.Context, query string, args ...interface{}) ([]models.Achievement, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	defer rows.Close()
+
+	var achievements []models.Achievement
+	for rows.Next() {
+		var a models.Achievement
+		if err := rows.Scan(&a.ID, &a.Name, &a.Description, &a.Kind, &a.GameID, &a.Category, &a.Threshold); err != nil {
+			return nil, fmt.Errorf("failed to scan achievement: %w", err)
+		}
+		achievements = append(achievements, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+
+	return achievements, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"retro-games-backend/internal/models"
+)
+
+func TestAchievementMet(t *testing.T) {
+	games := []models.Game{
+		{ID: "snake", Category: "arcade", Scoring: models.Scoring{Direction: models.ScoreHigherIsBetter}},
+		{ID: "pacman", Category: "arcade", Scoring: models.Scoring{Direction: models.ScoreHigherIsBetter}},
+		{ID: "speedrun", Category: "racing", Scoring: models.Scoring{Direction: models.ScoreLowerIsBetter}},
+	}
+	progress := playerProgress{
+		games: map[string]gameProgress{
+			"snake":    {plays: 12, bestScore: 500, bestRank: 3},
+			"pacman":   {plays: 2, bestScore: 9000},
+			"speedrun": {plays: 5, bestScore: 42000, bestRank: 1},
+		},
+		longestStreak: 7,
+	}
+
+	tests := []struct {
+		name        string
+		achievement models.Achievement
+		want        bool
+	}{
+		{"score reached", models.Achievement{Kind: models.AchievementScore, GameID: "snake", Threshold: 500}, true},
+		{"score not reached", models.Achievement{Kind: models.AchievementScore, GameID: "snake", Threshold: 501}, false},
+		{"time under the threshold", models.Achievement{Kind: models.AchievementScore, GameID: "speedrun", Threshold: 45000}, true},
+		{"time at the threshold", models.Achievement{Kind: models.AchievementScore, GameID: "speedrun", Threshold: 42000}, true},
+		{"time over the threshold", models.Achievement{Kind: models.AchievementScore, GameID: "speedrun", Threshold: 40000}, false},
+		{"score in any game of a category", models.Achievement{Kind: models.AchievementScore, Category: "arcade", Threshold: 5000}, true},
+		{"rank reached", models.Achievement{Kind: models.AchievementRank, GameID: "snake", Threshold: 3}, true},
+		{"never ranked", models.Achievement{Kind: models.AchievementRank, GameID: "pacman", Threshold: 10}, false},
+		{"plays across a category", models.Achievement{Kind: models.AchievementPlays, Category: "arcade", Threshold: 14}, true},
+		{"too few plays", models.Achievement{Kind: models.AchievementPlays, Category: "arcade", Threshold: 15}, false},
+		{"played every game", models.Achievement{Kind: models.AchievementPlayAll, Threshold: 2}, true},
+		{"one game played too little", models.Achievement{Kind: models.AchievementPlayAll, Threshold: 3}, false},
+		{"no games in the category", models.Achievement{Kind: models.AchievementPlayAll, Category: "sports", Threshold: 1}, false},
+		{"streak reached", models.Achievement{Kind: models.AchievementStreak, Threshold: 7}, true},
+		{"streak not reached", models.Achievement{Kind: models.AchievementStreak, Threshold: 8}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := achievementMet(tt.achievement, progress, games); got != tt.want {
+				t.Errorf("achievementMet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
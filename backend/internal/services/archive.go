@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// archiveDepth is the number of standings kept for each closed period
+const archiveDepth = 100
+
+// archiveLookback is how many closed windows of each period are checked on
+// every run, so periods that closed while the server was down still get archived
+var archiveLookback = map[models.LeaderboardPeriod]int{
+	models.PeriodDaily:   7,
+	models.PeriodWeekly:  4,
+	models.PeriodMonthly: 2,
+}
+
+// RunArchiver archives closed leaderboard periods every interval until ctx is cancelled
+func (l *LeaderboardService) RunArchiver(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if archived, err := l.ArchiveClosedPeriods(ctx); err != nil {
+			log.Printf("Failed to archive leaderboard periods: %v", err)
+		} else if archived > 0 {
+			log.Printf("Archived %d leaderboard standings", archived)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// archiveQuery copies the final best-per-player standings of a closed period
+// into leaderboard_archives for every game not yet archived for it, or only
+// for the game $6 when it is set
+const archiveQuery = `
+	INSERT INTO leaderboard_archives
+	    (game_id, period, period_key, period_start, period_end, rank, score_id, player_id, score, achieved_at)
+	SELECT game_id, $1, $2, $3, $4, rank, id, player_id, score, achieved_at
+	FROM (
+	    SELECT best.*,
+	           ROW_NUMBER() OVER (PARTITION BY best.game_id ORDER BY best.sort_key DESC, best.achieved_at ASC) AS rank
+	    FROM (
+	        SELECT DISTINCT ON (s.game_id, s.player_id) s.id, s.game_id, s.player_id, s.score, s.achieved_at,
+	               CASE WHEN g.score_direction = 'lower' THEN -s.score ELSE s.score END AS sort_key
+	        FROM scores s
+	        JOIN games g ON g.id = s.game_id
+	        WHERE s.achieved_at >= $3 AND s.achieved_at < $4
+	          AND s.status = 'accepted'
+	          AND ($6::text = '' OR s.game_id = $6::text)
+	          AND NOT EXISTS (
+	              SELECT 1 FROM leaderboard_archives a
+	              WHERE a.game_id = s.game_id AND a.period = $1 AND a.period_key = $2
+	          )
+	        ORDER BY s.game_id, s.player_id, sort_key DESC, s.achieved_at ASC
+	    ) best
+	) ranked
+	WHERE rank <= $5
+	ON CONFLICT DO NOTHING
+`
+
+// ArchiveClosedPeriods copies the final best-per-player standings of every
+// recently closed period into leaderboard_archives and returns the number of
+// rows written. Periods that are already archived are skipped, so it is safe
+// to run from several replicas at once.
+func (l *LeaderboardService) ArchiveClosedPeriods(ctx context.Context) (int, error) {
+	total := 0
+	for _, period := range models.ClosablePeriods {
+		for _, b := range l.periods.closed(period, archiveLookback[period]) {
+			tag, err := l.db.Exec(ctx, archiveQuery, string(period), b.PeriodKey, b.Start, b.End, archiveDepth, "")
+			if err != nil {
+				return total, fmt.Errorf("failed to archive %s %s: %w", period, b.PeriodKey, err)
+			}
+			total += int(tag.RowsAffected())
+		}
+	}
+
+	return total, nil
+}
+
+// rearchivePeriod replaces a game's archived standings for a closed period,
+// such as when scores played offline arrive after it was archived
+func rearchivePeriod(ctx context.Context, db *pgxpool.Pool, gameID string, b board) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `DELETE FROM leaderboard_archives WHERE game_id = $1 AND period = $2 AND period_key = $3`
+	if _, err := tx.Exec(ctx, query, gameID, string(b.Period), b.PeriodKey); err != nil {
+		return fmt.Errorf("failed to clear archived %s %s: %w", b.Period, b.PeriodKey, err)
+	}
+	if _, err := tx.Exec(ctx, archiveQuery, string(b.Period), b.PeriodKey, b.Start, b.End, archiveDepth, gameID); err != nil {
+		return fmt.Errorf("failed to archive %s %s: %w", b.Period, b.PeriodKey, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetArchivedPeriods returns the top entries of a game's most recent closed periods
+func (l *LeaderboardService) GetArchivedPeriods(ctx context.Context, gameID string, period models.LeaderboardPeriod, periods, top int) (*models.ArchiveListResponse, error) {
+	query := `
+		SELECT a.period_key, a.period_start, a.period_end,
+		       a.rank, a.score, COALESCE(a.player_id::text, ''),
+		       COALESCE(pp.display_name, ''), COALESCE(pp.avatar, ''), COALESCE(pp.country, ''), a.achieved_at
+		FROM leaderboard_archives a
+		LEFT JOIN player_profiles pp ON pp.player_id = a.player_id
+		JOIN (
+		    SELECT DISTINCT period_key, period_start
+		    FROM leaderboard_archives
+		    WHERE game_id = $1 AND period = $2
+		    ORDER BY period_start DESC
+		    LIMIT $3
+		) recent ON recent.period_key = a.period_key
+		WHERE a.game_id = $1 AND a.period = $2 AND a.rank <= $4
+		ORDER BY a.period_start DESC, a.rank ASC
+	`
+
+	rows, err := l.db.Query(ctx, query, gameID, string(period), periods, top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch archived periods: %w", err)
+	}
+	defer rows.Close()
+
+	archived := []models.ArchivedPeriod{}
+	for rows.Next() {
+		var key string
+		var start, end time.Time
+		var entry models.LeaderboardEntry
+		var playerID string
+
+		err := rows.Scan(&key, &start, &end, &entry.Rank, &entry.Score, &playerID, &entry.DisplayName, &entry.Avatar, &entry.Country, &entry.AchievedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan archived entry: %w", err)
+		}
+		entry.PlayerID = shortPlayerID(playerID)
+
+		if n := len(archived); n == 0 || archived[n-1].PeriodKey != key {
+			archived = append(archived, models.ArchivedPeriod{
+				PeriodKey:   key,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				Entries:     []models.LeaderboardEntry{},
+			})
+		}
+		last := &archived[len(archived)-1]
+		last.Entries = append(last.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch archived periods: %w", err)
+	}
+
+	return &models.ArchiveListResponse{
+		GameID:  gameID,
+		Period:  period,
+		Periods: archived,
+		Total:   len(archived),
+	}, nil
+}
+
+// GetArchivedStandings returns the full final standings of one closed period
+func (l *LeaderboardService) GetArchivedStandings(ctx context.Context, gameID string, period models.LeaderboardPeriod, periodKey string) (*models.ArchivedPeriod, error) {
+	query := `
+		SELECT a.period_start, a.period_end, a.rank, a.score, COALESCE(a.player_id::text, ''),
+		       COALESCE(pp.display_name, ''), COALESCE(pp.avatar, ''), COALESCE(pp.country, ''), a.achieved_at
+		FROM leaderboard_archives a
+		LEFT JOIN player_profiles pp ON pp.player_id = a.player_id
+		WHERE a.game_id = $1 AND a.period = $2 AND a.period_key = $3
+		ORDER BY a.rank ASC
+	`
+
+	rows, err := l.db.Query(ctx, query, gameID, string(period), periodKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch archived standings: %w", err)
+	}
+	defer rows.Close()
+
+	standings := &models.ArchivedPeriod{
+		PeriodKey: periodKey,
+		Entries:   []models.LeaderboardEntry{},
+	}
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		var playerID string
+
+		err := rows.Scan(&standings.PeriodStart, &standings.PeriodEnd, &entry.Rank, &entry.Score, &playerID, &entry.DisplayName, &entry.Avatar, &entry.Country, &entry.AchievedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan archived entry: %w", err)
+		}
+		entry.PlayerID = shortPlayerID(playerID)
+		standings.Entries = append(standings.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch archived standings: %w", err)
+	}
+
+	if len(standings.Entries) == 0 {
+		return nil, ErrPeriodNotArchived
+	}
+
+	return standings, nil
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"retro-games-backend/internal/models"
+)
+
+// batchedScore is a score of a batch recorded within its transaction
+type batchedScore struct {
+	index    int
+	game     models.Game
+	req      models.ScoreSubmissionRequest
+	recorded *recordedScore
+}
+
+// SubmitBatch records scores played offline. Each score is verified as
+// SubmitScore would, timed by its client timestamp, and written in a single
+// transaction; a score that is invalid, rejected or a duplicate does not
+// affect the others. Rankings, personal bests and caches are then updated
+// once per game rather than once per score.
+func (s *ScoreService) SubmitBatch(ctx context.Context, identity models.Identity, items []models.BatchScoreItem) (*models.BatchScoreResponse, error) {
+	results := make([]models.BatchScoreResult, len(items))
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var batched []batchedScore
+	var duplicates []int
+	seenKeys := make(map[string]bool)
+
+	for i, item := range items {
+		req := item.ScoreSubmissionRequest
+		results[i].Index = i
+
+		game, err := s.games.Get(ctx, req.GameID)
+		if errors.Is(err, ErrUnknownGame) {
+			results[i].Result, results[i].Error = models.BatchInvalid, "unknown game"
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if req.ClientRunID != "" {
+			if seenKeys[req.ClientRunID] {
+				results[i].Result, results[i].Error = models.BatchInvalid, "client_run_id repeated within the batch"
+				continue
+			}
+			seenKeys[req.ClientRunID] = true
+		}
+
+		// Each score gets a savepoint so one that fails leaves the rest intact
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+		}
+
+		recorded, err := s.recordScore(ctx, savepoint, identity, game, req, item.AchievedAt)
+		var rejected *SubmissionRejectedError
+		switch {
+		case err == nil:
+			batched = append(batched, batchedScore{index: i, game: game, req: req, recorded: recorded})
+			results[i].Result = models.BatchRecorded
+		case errors.As(err, &rejected):
+			// Keep the rejection record and the spent ticket
+			results[i].Result, results[i].Reason = models.BatchRejected, rejected.Reason
+		case errors.Is(err, errDuplicateSubmission):
+			savepoint.Rollback(ctx)
+			duplicates = append(duplicates, i)
+			continue
+		case errors.Is(err, ErrInvalidMetadata):
+			savepoint.Rollback(ctx)
+			results[i].Result, results[i].Error = models.BatchInvalid, err.Error()
+			continue
+		default:
+			return nil, err
+		}
+
+		if err := savepoint.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to submit scores: %w", err)
+	}
+
+	s.rankBatch(ctx, identity, batched, results)
+
+	// Unlock the achievements the batch earned, crediting its last accepted score
+	var achievements []models.AchievementUnlock
+	for i := len(batched) - 1; i >= 0; i-- {
+		if batched[i].recorded.Status == models.ScoreAccepted {
+			achievements, _ = s.achievements.UnlockEarned(ctx, identity.PlayerID, batched[i].recorded.ID)
+			break
+		}
+	}
+
+	// Earlier submissions answer for repeated ones
+	for _, i := range duplicates {
+		response, err := s.previousSubmission(ctx, identity, items[i].ScoreSubmissionRequest)
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			results[i].Result, results[i].Error = models.BatchInvalid, err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[i].Result, results[i].Score = models.BatchDuplicate, response
+	}
+
+	return &models.BatchScoreResponse{
+		Results:      results,
+		Recorded:     len(batched),
+		Achievements: achievements,
+	}, nil
+}
+
+// rankBatch adds a batch's accepted scores to the rankings and fills in
+// their responses, reading personal bests and ranks and invalidating caches
+// once per game
+func (s *ScoreService) rankBatch(ctx context.Context, identity models.Identity, batched []batchedScore, results []models.BatchScoreResult) {
+	// Group the batch by game, keeping the order games first appear in
+	byGame := make(map[string][]batchedScore)
+	var gameIDs []string
+	for _, score := range batched {
+		if _, ok := byGame[score.game.ID]; !ok {
+			gameIDs = append(gameIDs, score.game.ID)
+		}
+		byGame[score.game.ID] = append(byGame[score.game.ID], score)
+	}
+
+	for _, gameID := range gameIDs {
+		scores := byGame[gameID]
+		game := scores[0].game
+
+		// Add every accepted score; the last rank read back is the player's current one
+		var rank int
+		var rankErr error
+		ranked := false
+		for _, score := range scores {
+			if score.recorded.Status != models.ScoreAccepted {
+				continue
+			}
+			ranked = true
+			if rankErr != nil {
+				continue
+			}
+			rank, rankErr = s.addToRankings(ctx, game, scoreVariants(score.recorded.Metadata), rankedScore{
+				ScoreID:    score.recorded.ID,
+				PlayerID:   identity.PlayerID,
+				Score:      score.req.Score,
+				AchievedAt: score.recorded.AchievedAt,
+			})
+		}
+
+		// Get personal best, dropping the cached value the batch may have beaten
+		if ranked {
+			s.redis.Del(ctx, personalBestKey(identity.PlayerID, gameID))
+		}
+		personalBest, err := s.GetPersonalBest(ctx, identity.PlayerID, gameID)
+		if err != nil {
+			personalBest = 0
+		}
+
+		if ranked && rankErr != nil {
+			// Fall back to counting in Postgres if Redis is unavailable
+			rank, err = s.getScoreRank(ctx, game, personalBest)
+			if err != nil {
+				rank = 0 // If error, don't show rank
+			}
+		}
+		if ranked {
+			recordBestRank(ctx, s.db, identity.PlayerID, gameID, rank)
+		}
+
+		for _, score := range scores {
+			response := &models.ScoreResponse{
+				GameID:       gameID,
+				Score:        score.req.Score,
+				Status:       score.recorded.Status,
+				FlagReason:   score.recorded.FlagReason,
+				PersonalBest: personalBest,
+				AchievedAt:   score.recorded.AchievedAt,
+				XP:           score.recorded.XP,
+			}
+			if score.recorded.Status == models.ScoreAccepted {
+				response.Rank = rank
+			}
+			if score.req.ClientRunID != "" {
+				s.storeSubmissionResponse(ctx, identity.SessionID, score.req.ClientRunID, response)
+			}
+			results[score.index].Score = response
+		}
+
+		if ranked {
+			s.invalidateGameCache(ctx, gameID)
+			s.rearchiveLateScores(ctx, game, scores)
+		}
+	}
+}
+
+// rearchiveLateScores rewrites the archived standings of every closed period
+// a game's accepted scores from a batch fall in
+func (s *ScoreService) rearchiveLateScores(ctx context.Context, game models.Game, scores []batchedScore) {
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, score := range scores {
+		if score.recorded.Status != models.ScoreAccepted {
+			continue
+		}
+		for _, period := range models.ClosablePeriods {
+			b := s.periods.board(game.ID, models.ModeBestPerPlayer, period, score.recorded.AchievedAt)
+			if b.End.After(now) || seen[b.key()] {
+				continue
+			}
+			seen[b.key()] = true
+			if err := rearchivePeriod(ctx, s.db, game.ID, b); err != nil {
+				log.Printf("Failed to re-archive %s: %v", b.key(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// catalogTTL is how long the in-memory game catalogue is trusted before reloading
+const catalogTTL = 5 * time.Minute
+
+// catalogMissReload is the minimum gap between reloads triggered by unknown game IDs
+const catalogMissReload = 30 * time.Second
+
+// ErrUnknownGame is returned for game IDs that do not exist or are disabled
+var ErrUnknownGame = errors.New("unknown game")
+
+// ErrUnknownCategory is returned for categories with no enabled games
+var ErrUnknownCategory = errors.New("unknown category")
+
+// gameCatalog keeps the small, rarely changing games table in memory so
+// that scoring metadata can be consulted on every request without a query
+type gameCatalog struct {
+	db       *pgxpool.Pool
+	mu       sync.RWMutex
+	games    map[string]models.Game
+	loadedAt time.Time
+}
+
+// newGameCatalog creates a lazily loaded game catalogue
+func newGameCatalog(db *pgxpool.Pool) *gameCatalog {
+	return &gameCatalog{db: db}
+}
+
+// Get returns an enabled game by ID
+func (c *gameCatalog) Get(ctx context.Context, gameID string) (models.Game, error) {
+	c.mu.RLock()
+	game, ok := c.games[gameID]
+	age := time.Since(c.loadedAt)
+	c.mu.RUnlock()
+
+	if ok && age < catalogTTL {
+		return game, nil
+	}
+	if !ok && c.games != nil && age < catalogMissReload {
+		return models.Game{}, ErrUnknownGame
+	}
+
+	if err := c.reload(ctx); err != nil {
+		if ok {
+			return game, nil // Serve stale metadata rather than fail
+		}
+		return models.Game{}, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if game, ok := c.games[gameID]; ok {
+		return game, nil
+	}
+	return models.Game{}, ErrUnknownGame
+}
+
+// All returns every enabled game
+func (c *gameCatalog) All(ctx context.Context) ([]models.Game, error) {
+	c.mu.RLock()
+	fresh := c.games != nil && time.Since(c.loadedAt) < catalogTTL
+	c.mu.RUnlock()
+
+	if !fresh {
+		if err := c.reload(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	games := make([]models.Game, 0, len(c.games))
+	for _, game := range c.games {
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// Category returns every enabled game in a category
+func (c *gameCatalog) Category(ctx context.Context, category string) ([]models.Game, error) {
+	games, err := c.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []models.Game
+	for _, game := range games {
+		if game.Category == category {
+			matched = append(matched, game)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, ErrUnknownCategory
+	}
+	return matched, nil
+}
+
+// reload replaces the catalogue with the current contents of the games table
+func (c *gameCatalog) reload(ctx context.Context) error {
+	query := `
+		SELECT id, name, category, enabled, score_direction, score_unit, score_format,
+		       modes, difficulties, stats_schema,
+		       COALESCE(max_score, 0), COALESCE(max_points_per_second, 0), score_granularity, min_run_ms,
+		       xp_min, xp_max, xp_curve, created_at
+		FROM games
+		WHERE enabled = true
+	`
+
+	rows, err := c.db.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to load games: %w", err)
+	}
+	defer rows.Close()
+
+	games := make(map[string]models.Game)
+	for rows.Next() {
+		var game models.Game
+		var minRunMs int
+		err := rows.Scan(
+			&game.ID, &game.Name, &game.Category, &game.Enabled,
+			&game.Scoring.Direction, &game.Scoring.Unit, &game.Scoring.Format,
+			&game.Metadata.Modes, &game.Metadata.Difficulties, &game.Metadata.Stats,
+			&game.Rules.MaxScore, &game.Rules.MaxPointsPerSecond, &game.Rules.Granularity, &minRunMs,
+			&game.XP.Min, &game.XP.Max, &game.XP.Exponent, &game.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan game: %w", err)
+		}
+		game.Rules.MinRunDuration = time.Duration(minRunMs) * time.Millisecond
+		games[game.ID] = game
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load games: %w", err)
+	}
+
+	c.mu.Lock()
+	c.games = games
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
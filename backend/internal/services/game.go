@@ -41,7 +41,8 @@ func (g *GameService) GetAllGames(ctx context.Context) (*models.GamesListRespons
 
 	// Fallback to database
 	query := `
-		SELECT id, name, category, enabled, created_at 
+		SELECT id, name, category, enabled, score_direction, score_unit, score_format,
+		       modes, difficulties, stats_schema, created_at
 		FROM games 
 		WHERE enabled = true 
 		ORDER BY category, name
@@ -56,7 +57,11 @@ func (g *GameService) GetAllGames(ctx context.Context) (*models.GamesListRespons
 	var games []models.Game
 	for rows.Next() {
 		var game models.Game
-		err := rows.Scan(&game.ID, &game.Name, &game.Category, &game.Enabled, &game.CreatedAt)
+		err := rows.Scan(
+			&game.ID, &game.Name, &game.Category, &game.Enabled,
+			&game.Scoring.Direction, &game.Scoring.Unit, &game.Scoring.Format,
+			&game.Metadata.Modes, &game.Metadata.Difficulties, &game.Metadata.Stats, &game.CreatedAt,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan game: %w", err)
 		}
@@ -79,14 +84,17 @@ func (g *GameService) GetAllGames(ctx context.Context) (*models.GamesListRespons
 // GetGameByID returns a specific game by ID
 func (g *GameService) GetGameByID(ctx context.Context, gameID string) (*models.Game, error) {
 	query := `
-		SELECT id, name, category, enabled, created_at 
+		SELECT id, name, category, enabled, score_direction, score_unit, score_format,
+		       modes, difficulties, stats_schema, created_at
 		FROM games 
 		WHERE id = $1 AND enabled = true
 	`
 
 	var game models.Game
 	err := g.db.QueryRow(ctx, query, gameID).Scan(
-		&game.ID, &game.Name, &game.Category, &game.Enabled, &game.CreatedAt,
+		&game.ID, &game.Name, &game.Category, &game.Enabled,
+		&game.Scoring.Direction, &game.Scoring.Unit, &game.Scoring.Format,
+		&game.Metadata.Modes, &game.Metadata.Difficulties, &game.Metadata.Stats, &game.CreatedAt,
 	)
 	
 	if err != nil {
@@ -94,4 +102,55 @@ func (g *GameService) GetGameByID(ctx context.Context, gameID string) (*models.G
 	}
 
 	return &game, nil
-}
\ No newline at end of file
+}
+// GetCategories returns every category that has enabled games, with its game count
+func (g *GameService) GetCategories(ctx context.Context) (*models.CategoriesListResponse, error) {
+	// Try Redis cache first
+	cacheKey := "games:categories"
+	cached, err := g.redis.Get(ctx, cacheKey).Result()
+	if err == nil {
+		var response models.CategoriesListResponse
+		if json.Unmarshal([]byte(cached), &response) == nil {
+			return &response, nil
+		}
+	}
+
+	// Fallback to database
+	query := `
+		SELECT category, COUNT(*), ARRAY_AGG(id ORDER BY name)
+		FROM games
+		WHERE enabled = true
+		GROUP BY category
+		ORDER BY category
+	`
+
+	rows, err := g.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.ID, &category.GameCount, &category.GameIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+
+	response := &models.CategoriesListResponse{
+		Categories: categories,
+		Total:      len(categories),
+	}
+
+	// Cache result for 15 minutes
+	if responseJSON, err := json.Marshal(response); err == nil {
+		g.redis.Set(ctx, cacheKey, responseJSON, 15*time.Minute)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for history cursors that were not issued for
+// the requested sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// historyCursor marks the last score on a page of history. Key is the
+// score's sort key: its score in the game's direction when sorting by
+// score, and 0 when sorting by date.
+type historyCursor struct {
+	Sort       models.HistorySort `json:"o"`
+	Key        int                `json:"k"`
+	AchievedAt time.Time          `json:"t"`
+	ID         uuid.UUID          `json:"i"`
+}
+
+// encode returns the cursor as an opaque URL-safe string
+func (c historyCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeHistoryCursor parses a cursor returned with an earlier page of the
+// same sort
+func decodeHistoryCursor(value string, sort models.HistorySort) (*historyCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor historyCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// GetUserScores pages through a player's scores in one game, best first or
+// newest first
+func (s *ScoreService) GetUserScores(ctx context.Context, playerID uuid.UUID, q models.ScoreHistoryQuery) (*models.UserScoresResponse, error) {
+	game, err := s.games.Get(ctx, q.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort by the score in the game's direction, or by date alone
+	sign := 0
+	if q.Sort == models.SortByScore {
+		sign = game.Scoring.Direction.Sign()
+	}
+	return s.scoreHistory(ctx, playerID, q, sign)
+}
+
+// GetRecentRuns pages through a player's scores across every game, newest
+// first
+func (s *ScoreService) GetRecentRuns(ctx context.Context, playerID uuid.UUID, q models.ScoreHistoryQuery) (*models.UserScoresResponse, error) {
+	q.GameID = ""
+	q.Sort = models.SortByDate
+	return s.scoreHistory(ctx, playerID, q, 0)
+}
+
+// scoreHistory reads a page of a player's scores, in one game if q names
+// one. Scores are ordered by score times sign, which is 0 to order by date
+// alone, with later scores first among equal ones.
+func (s *ScoreService) scoreHistory(ctx context.Context, playerID uuid.UUID, q models.ScoreHistoryQuery, sign int) (*models.UserScoresResponse, error) {
+	// Continue after the last score of the previous page
+	var after historyCursor
+	var afterAt *time.Time
+	if q.Cursor != "" {
+		cursor, err := decodeHistoryCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, err
+		}
+		after, afterAt = *cursor, &cursor.AchievedAt
+	}
+
+	// Fetch one score more than the page to learn whether another follows
+	query := `
+		SELECT id, player_id, game_id, score, status, achieved_at,
+		       duration_ms, level, COALESCE(difficulty, ''), COALESCE(game_mode, ''), stats
+		FROM scores
+		WHERE player_id = $1
+		  AND ($2 = '' OR game_id = $2)
+		  AND ($3 = '' OR game_mode = $3)
+		  AND ($4 = '' OR difficulty = $4)
+		  AND ($5::date IS NULL OR achieved_at >= $5::date)
+		  AND ($6::date IS NULL OR achieved_at < $6::date + 1)
+		  AND ($7::timestamp IS NULL OR (score * $8, achieved_at, id) < ($9, $7::timestamp, $10))
+		ORDER BY score * $8 DESC, achieved_at DESC, id DESC
+		LIMIT $11
+	`
+
+	rows, err := s.db.Query(ctx, query, playerID, q.GameID, q.Variant.GameMode, q.Variant.Difficulty,
+		q.From, q.To, afterAt, sign, after.Key, after.ID, q.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user scores: %w", err)
+	}
+	defer rows.Close()
+
+	scores := []models.Score{}
+	for rows.Next() {
+		var score models.Score
+		err := rows.Scan(
+			&score.ID, &score.PlayerID, &score.GameID, &score.Score, &score.Status, &score.AchievedAt,
+			&score.DurationMs, &score.Level, &score.Difficulty, &score.Mode, &score.Stats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan score: %w", err)
+		}
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user scores: %w", err)
+	}
+
+	response := &models.UserScoresResponse{Scores: scores}
+	if len(scores) > q.Limit {
+		response.Scores = scores[:q.Limit]
+		last := response.Scores[q.Limit-1]
+		response.NextCursor = historyCursor{
+			Sort:       q.Sort,
+			Key:        last.Score * sign,
+			AchievedAt: last.AchievedAt,
+			ID:         last.ID,
+		}.encode()
+	}
+	response.Total = len(response.Scores)
+
+	return response, nil
+}
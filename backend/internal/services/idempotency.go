@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrIdempotencyKeyReused is returned when a session reuses an idempotency
+// key for a different submission
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different submission")
+
+// claimSubmissionKey reserves a session's idempotency key within tx and
+// reports whether it was free. A concurrent submission holding the same key
+// blocks the claim until it commits or rolls back.
+func (s *ScoreService) claimSubmissionKey(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, key string) (bool, error) {
+	query := `
+		INSERT INTO submission_keys (session_id, idempotency_key)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id, idempotency_key) DO NOTHING
+	`
+
+	tag, err := tx.Exec(ctx, query, sessionID, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// attachSubmissionKey links a claimed idempotency key to the score it produced
+func (s *ScoreService) attachSubmissionKey(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, key string, scoreID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `UPDATE submission_keys SET score_id = $3 WHERE session_id = $1 AND idempotency_key = $2`, sessionID, key, scoreID)
+	if err != nil {
+		return fmt.Errorf("failed to record idempotency key: %w", err)
+	}
+	return nil
+}
+
+// releaseSubmissionKey frees a claimed idempotency key within tx, so a
+// rejected submission can be corrected and retried under the same key
+func (s *ScoreService) releaseSubmissionKey(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, key string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM submission_keys WHERE session_id = $1 AND idempotency_key = $2`, sessionID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// storeSubmissionResponse keeps the response a keyed submission received.
+// Failure is not fatal: previousSubmission rebuilds the response if needed.
+func (s *ScoreService) storeSubmissionResponse(ctx context.Context, sessionID uuid.UUID, key string, response *models.ScoreResponse) {
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	s.db.Exec(ctx, `UPDATE submission_keys SET response = $3 WHERE session_id = $1 AND idempotency_key = $2`, sessionID, key, responseJSON)
+}
+
+// previousSubmission returns the response of the submission that first used
+// a session's idempotency key. If the stored response is missing, it is
+// rebuilt from the score with the player's current rank.
+func (s *ScoreService) previousSubmission(ctx context.Context, identity models.Identity, req models.ScoreSubmissionRequest) (*models.ScoreResponse, error) {
+	query := `
+		SELECT k.response, s.game_id, s.score, s.status, COALESCE(s.flag_reason, ''), s.achieved_at
+		FROM submission_keys k
+		JOIN scores s ON s.id = k.score_id
+		WHERE k.session_id = $1 AND k.idempotency_key = $2
+	`
+
+	var stored []byte
+	var gameID, status, flagReason string
+	var score int
+	var achievedAt time.Time
+
+	err := s.db.QueryRow(ctx, query, identity.SessionID, req.ClientRunID).Scan(&stored, &gameID, &score, &status, &flagReason, &achievedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The score behind the key has since been deleted
+		return nil, ErrIdempotencyKeyReused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load previous submission: %w", err)
+	}
+	if gameID != req.GameID || score != req.Score {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	var response models.ScoreResponse
+	if stored == nil || json.Unmarshal(stored, &response) != nil {
+		response = models.ScoreResponse{
+			GameID:     gameID,
+			Score:      score,
+			Status:     status,
+			FlagReason: flagReason,
+			AchievedAt: achievedAt,
+		}
+		response.PersonalBest, _ = s.GetPersonalBest(ctx, identity.PlayerID, gameID)
+		if status == models.ScoreAccepted {
+			if game, err := s.games.Get(ctx, gameID); err == nil {
+				response.Rank, _ = s.getScoreRank(ctx, game, response.PersonalBest)
+			}
+		}
+	}
+	response.Duplicate = true
+
+	return &response, nil
+}
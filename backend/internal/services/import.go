@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"retro-games-backend/internal/models"
+)
+
+// ErrAlreadyImported is returned when a session has already imported its
+// legacy high scores
+var ErrAlreadyImported = errors.New("scores already imported")
+
+// Reasons a legacy high score can be skipped on import
+const (
+	SkipUnknownGame  = "unknown_game"
+	SkipInvalidScore = "invalid_score"
+)
+
+// maxImportedScore matches the largest score a submission may carry
+const maxImportedScore = 99999999
+
+// legacyScoreSuffixes end the localStorage keys the frontend stored high
+// scores under, e.g. "tetris_high_score" and "2048_best"
+var legacyScoreSuffixes = []string{"_high_score", "_best"}
+
+// legacyGameAliases maps legacy key prefixes that differ from a game ID
+var legacyGameAliases = map[string]string{
+	"2048": "game2048",
+}
+
+// ImportScores stores the high scores a session's browser kept in
+// localStorage for the session's player. They are marked imported: they
+// show in the player's own history but never on leaderboards. Keys that do
+// not name a game, and scores a game could not produce, are skipped. A
+// session can import once.
+func (s *ScoreService) ImportScores(ctx context.Context, identity models.Identity, scores map[string]int) (*models.ImportScoresResponse, error) {
+	response := &models.ImportScoresResponse{
+		Imported: []models.ImportedScore{},
+		Skipped:  []models.SkippedScore{},
+	}
+
+	// Import in key order so the response is stable
+	keys := make([]string, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		score := scores[key]
+		game, err := s.games.Get(ctx, legacyGameID(key))
+		if errors.Is(err, ErrUnknownGame) {
+			response.Skipped = append(response.Skipped, models.SkippedScore{Key: key, Reason: SkipUnknownGame})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if score <= 0 || score > maxImportedScore {
+			response.Skipped = append(response.Skipped, models.SkippedScore{Key: key, Reason: SkipInvalidScore})
+			continue
+		}
+		if game.Rules.MaxScore > 0 && score > game.Rules.MaxScore {
+			response.Skipped = append(response.Skipped, models.SkippedScore{Key: key, Reason: FlagAboveMaxScore})
+			continue
+		}
+		if game.Rules.Granularity > 1 && score%game.Rules.Granularity != 0 {
+			response.Skipped = append(response.Skipped, models.SkippedScore{Key: key, Reason: FlagGranularity})
+			continue
+		}
+
+		response.Imported = append(response.Imported, models.ImportedScore{Key: key, GameID: game.ID, Score: score})
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Mark the session as imported, refusing a second import
+	tag, err := tx.Exec(ctx, `UPDATE sessions SET scores_imported_at = CURRENT_TIMESTAMP WHERE id = $1 AND scores_imported_at IS NULL`, identity.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import scores: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrAlreadyImported
+	}
+
+	for _, imported := range response.Imported {
+		_, err := tx.Exec(ctx, `INSERT INTO scores (session_id, player_id, game_id, score, status) VALUES ($1, $2, $3, $4, $5)`,
+			identity.SessionID, identity.PlayerID, imported.GameID, imported.Score, models.ScoreImported)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import score: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to import scores: %w", err)
+	}
+
+	return response, nil
+}
+
+// legacyGameID maps a localStorage high score key to the game it belongs to
+func legacyGameID(key string) string {
+	for _, suffix := range legacyScoreSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			key = strings.TrimSuffix(key, suffix)
+			break
+		}
+	}
+	if gameID, ok := legacyGameAliases[key]; ok {
+		return gameID
+	}
+	return strings.ReplaceAll(key, "_", "-")
+}
@@ -0,0 +1,79 @@
+package services
+
+import "testing"
+
+func TestLegacyGameID(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		// Every key the frontend stored high scores under
+		{"2048_high_score", "game2048"},
+		{"air_hockey_high_score", "air-hockey"},
+		{"asteroids_high_score", "asteroids"},
+		{"basketball_high_score", "basketball"},
+		{"bowling_high_score", "bowling"},
+		{"breakout_high_score", "breakout"},
+		{"centipede_high_score", "centipede"},
+		{"centipede_shooter_high_score", "centipede-shooter"},
+		{"circuit_racer_high_score", "circuit-racer"},
+		{"connect_four_high_score", "connect-four"},
+		{"defender_high_score", "defender"},
+		{"desert_rally_high_score", "desert-rally"},
+		{"drag_racing_high_score", "drag-racing"},
+		{"f1_racing_high_score", "f1-racing"},
+		{"frogger_high_score", "frogger"},
+		{"galaga_high_score", "galaga"},
+		{"golf_high_score", "golf"},
+		{"laser_defense_high_score", "laser-defense"},
+		{"match3_high_score", "match3"},
+		{"missile_command_high_score", "missile-command"},
+		{"missile_defense_high_score", "missile-defense"},
+		{"mountain_racing_high_score", "mountain-racing"},
+		{"pacman_high_score", "pacman"},
+		{"phoenix_high_score", "phoenix"},
+		{"pong_high_score", "pong"},
+		{"road_racer_high_score", "road-racer"},
+		{"sliding_puzzle_high_score", "sliding-puzzle"},
+		{"snake_high_score", "snake"},
+		{"soccer_high_score", "soccer"},
+		{"sokoban_high_score", "sokoban"},
+		{"space_invaders_high_score", "space-invaders"},
+		{"speed_chase_high_score", "speed-chase"},
+		{"sudoku_high_score", "sudoku"},
+		{"tennis_high_score", "tennis"},
+		{"tetris_high_score", "tetris"},
+
+		// The other suffix
+		{"2048_best", "game2048"},
+		{"air_hockey_best", "air-hockey"},
+		{"tetris_best", "tetris"},
+
+		// Only one suffix is stripped, and only at the end
+		{"tetris_best_high_score", "tetris-best"},
+		{"tetris_high_score_best", "tetris-high-score"},
+		{"high_score_tetris", "high-score-tetris"},
+
+		// Keys without a suffix still map, to be skipped if unknown
+		{"2048", "game2048"},
+		{"space_invaders", "space-invaders"},
+		{"settings", "settings"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := legacyGameID(tt.key); got != tt.want {
+			t.Errorf("legacyGameID(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLegacyGameAliases(t *testing.T) {
+	for alias, gameID := range legacyGameAliases {
+		for _, suffix := range legacyScoreSuffixes {
+			if got := legacyGameID(alias + suffix); got != gameID {
+				t.Errorf("legacyGameID(%q) = %q, want %q", alias+suffix, got, gameID)
+			}
+		}
+	}
+}
@@ -3,92 +3,359 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"retro-games-backend/internal/models"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrNoScores is returned when a player has no ranked score for a game
+var ErrNoScores = errors.New("no scores recorded")
+
+// ErrPeriodNotArchived is returned when a closed period has no archived standings
+var ErrPeriodNotArchived = errors.New("period not archived")
+
+// ErrUnknownVariant is returned when a leaderboard is filtered by a mode or
+// difficulty the game does not offer
+var ErrUnknownVariant = errors.New("unknown game mode or difficulty")
+
+// rankPointsDecay is the share of the previous position's points awarded to
+// each lower rank on the global leaderboard: 100, 90, 81, ...
+const rankPointsDecay = 0.9
+
+// globalLeaderboardVersionKey is incremented when profiles change so no cached
+// global leaderboard shows an old display name
+const globalLeaderboardVersionKey = "leaderboard:global:version"
+
+// How long cached global leaderboards are kept. A category's ranking is
+// invalidated by submissions to its games, so it can be kept for longer; the
+// ranking of every game would be invalidated by every submission, so it is
+// kept briefly instead.
+const (
+	categoryLeaderboardCacheTTL = 5 * time.Minute
+	globalLeaderboardCacheTTL   = 30 * time.Second
+)
+
+// categoryLeaderboardVersionKey returns the key incremented on submissions to
+// a category's games, so its cached rankings are never served stale
+func categoryLeaderboardVersionKey(category string) string {
+	return fmt.Sprintf("leaderboard:category:%s:version", category)
+}
+
 // LeaderboardService handles leaderboard operations
 type LeaderboardService struct {
-	db    *pgxpool.Pool
-	redis *redis.Client
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	rankings *rankingIndex
+	periods  periodClock
+	games    *gameCatalog
 }
 
-// NewLeaderboardService creates a new leaderboard service
-func NewLeaderboardService(db *pgxpool.Pool, redis *redis.Client) *LeaderboardService {
+// NewLeaderboardService creates a new leaderboard service. Period boundaries
+// are computed in loc, or UTC if loc is nil.
+func NewLeaderboardService(db *pgxpool.Pool, redis *redis.Client, loc *time.Location) *LeaderboardService {
 	return &LeaderboardService{
-		db:    db,
-		redis: redis,
+		db:       db,
+		redis:    redis,
+		rankings: newRankingIndex(db, redis),
+		periods:  newPeriodClock(loc),
+		games:    newGameCatalog(db),
 	}
 }
 
-// GetGameLeaderboard gets the top scores for a specific game
-func (l *LeaderboardService) GetGameLeaderboard(ctx context.Context, gameID string, limit int) (*models.LeaderboardResponse, error) {
-	// Try Redis cache first
-	cacheKey := fmt.Sprintf("leaderboard:%s:%d", gameID, limit)
-	cached, err := l.redis.Get(ctx, cacheKey).Result()
-	
-	if err == nil {
-		var response models.LeaderboardResponse
-		if json.Unmarshal([]byte(cached), &response) == nil {
-			return &response, nil
+// GetGameLeaderboard gets the top scores for a specific game, variant and period
+func (l *LeaderboardService) GetGameLeaderboard(ctx context.Context, gameID string, variant models.ScoreVariant, mode models.LeaderboardMode, period models.LeaderboardPeriod, limit int) (*models.LeaderboardResponse, error) {
+	game, b, err := l.currentBoard(ctx, gameID, variant, mode, period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read ranks from the board's sorted set
+	ranked, err := l.rankings.Range(ctx, b, 0, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leaderboard: %w", err)
+	}
+
+	entries, err := l.hydrateEntries(ctx, ranked)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.LeaderboardResponse{
+		GameID:     gameID,
+		Mode:       mode,
+		Period:     period,
+		PeriodKey:  b.PeriodKey,
+		Scoring:    &game.Scoring,
+		GameMode:   variant.GameMode,
+		Difficulty: variant.Difficulty,
+		Entries:    entries,
+		Total:      len(entries),
+	}
+	if period != models.PeriodAllTime {
+		response.PeriodStart = &b.Start
+		response.PeriodEnd = &b.End
+	}
+
+	return response, nil
+}
+
+// GetLeaderboardAroundPlayer gets the entries ranked directly above and
+// below a player's best score for a game, variant and period
+func (l *LeaderboardService) GetLeaderboardAroundPlayer(ctx context.Context, playerID uuid.UUID, gameID string, variant models.ScoreVariant, mode models.LeaderboardMode, period models.LeaderboardPeriod, radius int) (*models.AroundMeResponse, error) {
+	game, b, err := l.currentBoard(ctx, gameID, variant, mode, period)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := l.playerMember(ctx, b, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := l.rankings.Count(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leaderboard: %w", err)
+	}
+
+	rank, err := l.rankings.Rank(ctx, b, member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rank: %w", err)
+	}
+	if rank == 0 {
+		return nil, ErrNoScores
+	}
+
+	// Read the window around the player's position
+	offset := rank - 1 - radius
+	if offset < 0 {
+		offset = 0
+	}
+	ranked, err := l.rankings.Range(ctx, b, offset, rank-offset+radius)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leaderboard: %w", err)
+	}
+
+	entries, err := l.hydrateEntries(ctx, ranked)
+	if err != nil {
+		return nil, err
+	}
+	var score int
+	for i := range entries {
+		entries[i].IsCurrent = entries[i].Rank == rank
+		if entries[i].IsCurrent {
+			score = entries[i].Score
 		}
 	}
 
-	// Fallback to database
+	return &models.AroundMeResponse{
+		GameID:       gameID,
+		Mode:         mode,
+		Period:       period,
+		PeriodKey:    b.PeriodKey,
+		Scoring:      &game.Scoring,
+		GameMode:     variant.GameMode,
+		Difficulty:   variant.Difficulty,
+		Rank:         rank,
+		Score:        score,
+		Percentile:   percentile(rank, total),
+		TotalEntries: total,
+		Entries:      entries,
+	}, nil
+}
+
+// currentBoard resolves a game and its variant's board for the current period
+func (l *LeaderboardService) currentBoard(ctx context.Context, gameID string, variant models.ScoreVariant, mode models.LeaderboardMode, period models.LeaderboardPeriod) (models.Game, board, error) {
+	game, err := l.games.Get(ctx, gameID)
+	if err != nil {
+		return models.Game{}, board{}, err
+	}
+	if !game.Metadata.Allows(variant) {
+		return models.Game{}, board{}, ErrUnknownVariant
+	}
+
+	b := l.periods.current(gameID, mode, period)
+	b.Variant = variant
+	b.Direction = game.Scoring.Direction
+	return game, b, nil
+}
+
+// playerMember finds the ranking member for a player's best run on a board
+func (l *LeaderboardService) playerMember(ctx context.Context, b board, playerID uuid.UUID) (string, error) {
+	if b.Mode == models.ModeBestPerPlayer {
+		member, err := l.rankings.PlayerMember(ctx, b, playerID)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch rank: %w", err)
+		}
+		if member == "" {
+			return "", ErrNoScores
+		}
+		return member, nil
+	}
+
+	// Find the player's best run within the period
+	start, end := b.bounds()
 	query := `
-		SELECT s.score, s.achieved_at, s.session_id,
-		       ROW_NUMBER() OVER (ORDER BY s.score DESC, s.achieved_at ASC) as rank
+		SELECT id, achieved_at
+		FROM scores
+		WHERE player_id = $1 AND game_id = $2 AND status = 'accepted'
+		  AND achieved_at >= $3 AND achieved_at < $4
+		  AND ($6::text = '' OR game_mode = $6::text)
+		  AND ($7::text = '' OR difficulty = $7::text)
+		ORDER BY score * $5 DESC, achieved_at ASC
+		LIMIT 1
+	`
+
+	var scoreID uuid.UUID
+	var achievedAt time.Time
+
+	err := l.db.QueryRow(ctx, query, playerID, b.GameID, start, end, b.sign(), b.Variant.GameMode, b.Variant.Difficulty).Scan(&scoreID, &achievedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNoScores
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get best score: %w", err)
+	}
+
+	return rankingMember(scoreID, achievedAt), nil
+}
+
+// RebuildGameLeaderboard repopulates a game's all-time and current period
+// rankings, for every variant, from Postgres and returns the number of
+// all-time runs
+func (l *LeaderboardService) RebuildGameLeaderboard(ctx context.Context, gameID string) (int, error) {
+	game, err := l.games.Get(ctx, gameID)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	for _, b := range l.periods.boardsFor(game, gameVariants(game), time.Now()) {
+		count, err := l.rankings.Rebuild(ctx, b)
+		if err != nil {
+			return 0, err
+		}
+		if b.Variant == (models.ScoreVariant{}) && b.Mode == models.ModeAllRuns && b.Period == models.PeriodAllTime {
+			total = count
+		}
+	}
+	return total, nil
+}
+
+// RebuildAllLeaderboards repopulates every game's ranking from Postgres
+func (l *LeaderboardService) RebuildAllLeaderboards(ctx context.Context) (map[string]int, error) {
+	games, err := l.games.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(games))
+	for _, game := range games {
+		count, err := l.RebuildGameLeaderboard(ctx, game.ID)
+		if err != nil {
+			return counts, fmt.Errorf("failed to rebuild %s: %w", game.ID, err)
+		}
+		counts[game.ID] = count
+	}
+
+	return counts, nil
+}
+
+// hydrateEntries loads the score rows behind ranked members from Postgres
+func (l *LeaderboardService) hydrateEntries(ctx context.Context, ranked []rankedMember) ([]models.LeaderboardEntry, error) {
+	entries := make([]models.LeaderboardEntry, 0, len(ranked))
+	if len(ranked) == 0 {
+		return entries, nil
+	}
+
+	ids := make([]uuid.UUID, len(ranked))
+	for i, member := range ranked {
+		ids[i] = member.ScoreID
+	}
+
+	query := `
+		SELECT s.id, s.player_id::text, COALESCE(pp.display_name, ''), COALESCE(pp.avatar, ''),
+		       COALESCE(pp.country, ''), s.achieved_at
 		FROM scores s
-		WHERE s.game_id = $1
-		ORDER BY s.score DESC, s.achieved_at ASC
-		LIMIT $2
+		LEFT JOIN player_profiles pp ON pp.player_id = s.player_id
+		WHERE s.id = ANY($1)
 	`
 
-	rows, err := l.db.Query(ctx, query, gameID, limit)
+	rows, err := l.db.Query(ctx, query, ids)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch leaderboard: %w", err)
+		return nil, fmt.Errorf("failed to fetch leaderboard entries: %w", err)
 	}
 	defer rows.Close()
 
-	var entries []models.LeaderboardEntry
+	type scoreRow struct {
+		playerID   string
+		card       models.PlayerCard
+		achievedAt time.Time
+	}
+	found := make(map[uuid.UUID]scoreRow, len(ranked))
 	for rows.Next() {
-		var entry models.LeaderboardEntry
-		var sessionID string
-		
-		err := rows.Scan(&entry.Score, &entry.AchievedAt, &sessionID, &entry.Rank)
-		if err != nil {
+		var id uuid.UUID
+		var row scoreRow
+		if err := rows.Scan(&id, &row.playerID, &row.card.DisplayName, &row.card.Avatar, &row.card.Country, &row.achievedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
 		}
-		
-		entry.SessionID = sessionID[:8] // Show only first 8 chars for privacy
-		entries = append(entries, entry)
+		found[id] = row
 	}
-
-	response := &models.LeaderboardResponse{
-		GameID:  gameID,
-		Entries: entries,
-		Total:   len(entries),
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch leaderboard entries: %w", err)
 	}
 
-	// Cache result for 5 minutes
-	if responseJSON, err := json.Marshal(response); err == nil {
-		l.redis.Set(ctx, cacheKey, responseJSON, 5*time.Minute)
+	for _, member := range ranked {
+		row, ok := found[member.ScoreID]
+		if !ok {
+			continue // Score deleted since it was ranked
+		}
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:       member.Rank,
+			Score:      member.Score,
+			PlayerID:   shortPlayerID(row.playerID),
+			PlayerCard: row.card,
+			AchievedAt: row.achievedAt,
+		})
 	}
 
-	return response, nil
+	return entries, nil
 }
 
-// GetGlobalLeaderboard gets the top scores across all games
-func (l *LeaderboardService) GetGlobalLeaderboard(ctx context.Context, limit int) (*models.GlobalLeaderboardResponse, error) {
-	// Try Redis cache first
-	cacheKey := fmt.Sprintf("leaderboard:global:%d", limit)
+// GetGlobalLeaderboard ranks players across every game, or every game in a
+// category, for a period. Each player's best score in a game is ranked
+// against the other players of that game and converted into points, either
+// by rank (100 for first, decaying by rankPointsDecay per position) or by
+// percentile, so games with very different score scales weigh the same.
+// A player's points are summed over every game they have played.
+func (l *LeaderboardService) GetGlobalLeaderboard(ctx context.Context, category string, method models.GlobalRankingMethod, period models.LeaderboardPeriod, limit int) (*models.GlobalLeaderboardResponse, error) {
+	if category != "" {
+		if _, err := l.games.Category(ctx, category); err != nil {
+			return nil, err
+		}
+	}
+
+	b := l.periods.current("", models.ModeBestPerPlayer, period)
+	start, end := b.bounds()
+
+	// Try Redis cache first; profile changes, and submissions to a category's
+	// games, bump the versions to invalidate it
+	version, _ := l.redis.Get(ctx, globalLeaderboardVersionKey).Int64()
+	var categoryVersion int64
+	ttl := globalLeaderboardCacheTTL
+	if category != "" {
+		categoryVersion, _ = l.redis.Get(ctx, categoryLeaderboardVersionKey(category)).Int64()
+		ttl = categoryLeaderboardCacheTTL
+	}
+	cacheKey := fmt.Sprintf("leaderboard:global:%d:%d:%s:%s:%s:%s:%d", version, categoryVersion, category, method, period, b.PeriodKey, limit)
 	cached, err := l.redis.Get(ctx, cacheKey).Result()
-	
 	if err == nil {
 		var response models.GlobalLeaderboardResponse
 		if json.Unmarshal([]byte(cached), &response) == nil {
@@ -98,42 +365,93 @@ func (l *LeaderboardService) GetGlobalLeaderboard(ctx context.Context, limit int
 
 	// Fallback to database
 	query := `
-		SELECT s.game_id, g.name, s.score, s.session_id, s.achieved_at
-		FROM scores s
-		JOIN games g ON s.game_id = g.id
-		ORDER BY s.score DESC, s.achieved_at ASC
-		LIMIT $1
+		WITH best AS (
+		    SELECT DISTINCT ON (s.game_id, s.player_id) s.game_id, s.player_id, s.achieved_at,
+		           CASE WHEN g.score_direction = 'lower' THEN -s.score ELSE s.score END AS sort_key
+		    FROM scores s
+		    JOIN games g ON g.id = s.game_id
+		    WHERE s.achieved_at >= $1 AND s.achieved_at < $2
+		      AND s.status = 'accepted'
+		      AND g.enabled = true
+		      AND ($3::text = '' OR g.category = $3::text)
+		    ORDER BY s.game_id, s.player_id, sort_key DESC, s.achieved_at ASC
+		), ranked AS (
+		    SELECT game_id, player_id,
+		           ROW_NUMBER() OVER (PARTITION BY game_id ORDER BY sort_key DESC, achieved_at ASC) AS rank,
+		           COUNT(*) OVER (PARTITION BY game_id) AS players
+		    FROM best
+		), points AS (
+		    SELECT game_id, player_id, rank,
+		           CASE WHEN $4::text = 'percentile'
+		                THEN 100.0 * (players - rank + 1) / players
+		                ELSE 100.0 * POWER($5::float8, rank - 1)
+		           END AS points
+		    FROM ranked
+		)
+		SELECT p.player_id::text, COALESCE(pp.display_name, ''), COALESCE(pp.avatar, ''), COALESCE(pp.country, ''),
+		       ROUND(SUM(points)::numeric, 2)::float8 AS total,
+		       COUNT(*) AS games_played,
+		       MIN(rank) AS best_rank,
+		       (ARRAY_AGG(game_id ORDER BY rank ASC, points DESC, game_id))[1] AS best_game_id
+		FROM points p
+		LEFT JOIN player_profiles pp ON pp.player_id = p.player_id
+		GROUP BY p.player_id, pp.display_name, pp.avatar, pp.country
+		ORDER BY total DESC, games_played DESC, best_rank ASC, p.player_id
+		LIMIT $6
 	`
 
-	rows, err := l.db.Query(ctx, query, limit)
+	rows, err := l.db.Query(ctx, query, start, end, category, string(method), rankPointsDecay, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch global leaderboard: %w", err)
 	}
 	defer rows.Close()
 
-	var entries []models.GlobalLeaderboardEntry
+	entries := []models.GlobalLeaderboardEntry{}
 	for rows.Next() {
 		var entry models.GlobalLeaderboardEntry
-		var sessionID string
-		
-		err := rows.Scan(&entry.GameID, &entry.GameName, &entry.Score, &sessionID, &entry.AchievedAt)
+		var playerID string
+
+		err := rows.Scan(&playerID, &entry.DisplayName, &entry.Avatar, &entry.Country, &entry.Points, &entry.GamesPlayed, &entry.BestRank, &entry.BestGameID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan global leaderboard entry: %w", err)
 		}
-		
-		entry.SessionID = sessionID[:8] // Show only first 8 chars for privacy
+
+		entry.Rank = len(entries) + 1
+		entry.PlayerID = shortPlayerID(playerID)
 		entries = append(entries, entry)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch global leaderboard: %w", err)
+	}
 
 	response := &models.GlobalLeaderboardResponse{
-		Entries: entries,
-		Total:   len(entries),
+		Category:  category,
+		Method:    method,
+		Period:    period,
+		PeriodKey: b.PeriodKey,
+		Entries:   entries,
+		Total:     len(entries),
 	}
 
-	// Cache result for 5 minutes
 	if responseJSON, err := json.Marshal(response); err == nil {
-		l.redis.Set(ctx, cacheKey, responseJSON, 5*time.Minute)
+		l.redis.Set(ctx, cacheKey, responseJSON, ttl)
 	}
 
 	return response, nil
-}
\ No newline at end of file
+}
+
+// shortPlayerID shows only the first 8 chars of a player ID for privacy
+func shortPlayerID(playerID string) string {
+	if len(playerID) < 8 {
+		return playerID
+	}
+	return playerID[:8]
+}
+
+// percentile returns the share of ranked entries at or below the given rank
+func percentile(rank, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(total-rank+1)/float64(total)*10000) / 100
+}
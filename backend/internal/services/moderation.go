@@ -0,0 +1,176 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reasons a profile field can be refused
+const (
+	RefuseInvalid       = "invalid"
+	RefuseProfanity     = "profanity"
+	RefuseImpersonation = "impersonation"
+	RefuseTaken         = "taken"
+	RefuseBlocked       = "blocked"
+)
+
+// displayNamePattern allows 3-24 ASCII letters, digits, '_', '-' and '.',
+// with single spaces between words. Keeping to ASCII rules out look-alike
+// letters from other scripts.
+var displayNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+( [A-Za-z0-9_.-]+)*$`)
+
+const (
+	minDisplayNameLength = 3
+	maxDisplayNameLength = 24
+)
+
+// nameKeyReplacer folds the digits and symbols commonly swapped for letters
+var nameKeyReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "@", "a", "$", "s",
+)
+
+// profaneTerms are refused at the start of any word of a display name or
+// bio, once folded by nameKey
+var profaneTerms = []string{
+	"fuck", "shit", "cunt", "bitch", "whore", "slut", "nigg", "fag", "retard",
+	"rapist", "nazi", "hitler", "asshole", "pussy", "penis", "dildo", "porn",
+}
+
+// staffTerms mark a display name as posing as the site or its staff when a
+// word of it starts with one
+var staffTerms = []string{
+	"admin", "moderator", "official", "staff", "support", "retrogames", "developer",
+}
+
+// allowedWords are innocent words that start with a refused term. A word
+// starting with one of them is not matched against the terms.
+var allowedWords = []string{
+	"nazim", "nazir", "stafford", "penistone", "niggl", "fagan", "fagin", "retardant", "shitake",
+}
+
+// staffNames are refused as whole display names only, being too short to
+// match inside others
+var staffNames = map[string]bool{
+	"mod": true, "dev": true, "root": true, "owner": true, "system": true, "server": true,
+}
+
+// nameKey folds a display name for comparison: lowercase, look-alike digits
+// and symbols read as letters, and everything but letters and digits dropped
+func nameKey(name string) string {
+	folded := nameKeyReplacer.Replace(strings.ToLower(name))
+
+	var key strings.Builder
+	for _, r := range folded {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+// nameWords splits text into the words it is written as, folded by nameKey:
+// at spaces and punctuation, and at capital letters that start a word, so
+// "BadmintonAce" is "badminton" and "ace" and "XAdmin" is "x" and "admin"
+func nameWords(text string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if key := nameKey(word.String()); key != "" {
+			words = append(words, key)
+		}
+		word.Reset()
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if isWordSeparator(r) {
+			flush()
+			continue
+		}
+		if isUpper(r) && i > 0 && !isWordSeparator(runes[i-1]) {
+			// A capital after a lowercase letter or digit, or a capital
+			// ending a run of them before a lowercase letter
+			if !isUpper(runes[i-1]) || (i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z') {
+				flush()
+			}
+		}
+		word.WriteRune(r)
+	}
+	flush()
+	return words
+}
+
+// isUpper reports whether r is an ASCII capital letter
+func isUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
+
+// isWordSeparator reports whether r separates words. Digits and symbols
+// nameKey reads as letters are part of words.
+func isWordSeparator(r rune) bool {
+	if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+		return false
+	}
+	return r != '@' && r != '$'
+}
+
+// checkDisplayName returns the reason a display name is refused, or an empty
+// string if it is allowed. Uniqueness is checked against the database
+// separately.
+func checkDisplayName(name string) string {
+	if len(name) < minDisplayNameLength || len(name) > maxDisplayNameLength || !displayNamePattern.MatchString(name) {
+		return RefuseInvalid
+	}
+
+	key := nameKey(name)
+	if len(key) < minDisplayNameLength {
+		return RefuseInvalid
+	}
+
+	words := nameWords(name)
+	if startsWithTerm(words, profaneTerms) {
+		return RefuseProfanity
+	}
+	if staffNames[key] || startsWithTerm(words, staffTerms) {
+		return RefuseImpersonation
+	}
+	return ""
+}
+
+// checkBio returns the reason a bio is refused, or an empty string if it is
+// allowed
+func checkBio(bio string) string {
+	for _, word := range strings.Fields(bio) {
+		if startsWithTerm(nameWords(word), profaneTerms) {
+			return RefuseProfanity
+		}
+	}
+	return ""
+}
+
+// startsWithTerm reports whether any of words, run together with the words
+// after it, starts with one of terms. Running words together catches a term
+// spelt out with spaces or capitals, such as "F U C K" or "AssHole". A run
+// starting with an allowed word is skipped.
+func startsWithTerm(words []string, terms []string) bool {
+	for i := range words {
+		run := strings.Join(words[i:], "")
+		if hasAnyPrefix(run, allowedWords) {
+			continue
+		}
+		if hasAnyPrefix(run, terms) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyPrefix reports whether s starts with any of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
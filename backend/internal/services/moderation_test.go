@@ -0,0 +1,119 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNameKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Ace_Pilot", "acepilot"},
+		{"ACE P1LOT", "acepilot"},
+		{"Ace.Pilot-2", "acepilot2"},
+		{"5H1T", "shit"},
+		{"@dm1n", "admin"},
+		{"$74ff", "staff"},
+		{"B0B", "bob"},
+		{"L3g3nd 8", "legendb"},
+	}
+
+	for _, tt := range tests {
+		if got := nameKey(tt.name); got != tt.want {
+			t.Errorf("nameKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"BadmintonAce", []string{"badminton", "ace"}},
+		{"ace_pilot", []string{"ace", "pilot"}},
+		{"xXAdminXx", []string{"x", "x", "admin", "xx"}},
+		{"HTTPServer", []string{"http", "server"}},
+		{"Player1Admin", []string{"playeri", "admin"}},
+		{"I'm", []string{"i", "m"}},
+		{"--", nil},
+	}
+
+	for _, tt := range tests {
+		if got := nameWords(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("nameWords(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDisplayName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		// Innocent names containing a refused term
+		{"Nazim", ""},
+		{"Scunthorpe", ""},
+		{"BadmintonAce", ""},
+		{"Staffordshire", ""},
+		{"Penistone FC", ""},
+		{"Fire Retardant", ""},
+		{"Ace_Pilot", ""},
+		{"Therapist", ""},
+		{"Modern Gamer", ""},
+		{"Devon", ""},
+
+		// Profanity, however it is spelt
+		{"fuckface", RefuseProfanity},
+		{"F U C K", RefuseProfanity},
+		{"5H1T", RefuseProfanity},
+		{"AssHole", RefuseProfanity},
+		{"Big Nazi", RefuseProfanity},
+		{"Nazim Nazi", RefuseProfanity},
+
+		// Posing as staff
+		{"Admin", RefuseImpersonation},
+		{"xXAdminXx", RefuseImpersonation},
+		{"4dm1n", RefuseImpersonation},
+		{"Official RetroGames", RefuseImpersonation},
+		{"StaffMember", RefuseImpersonation},
+		{"Stafford Admin", RefuseImpersonation},
+		{"mod", RefuseImpersonation},
+		{"M.O.D", RefuseImpersonation},
+
+		// Malformed
+		{"ab", RefuseInvalid},
+		{"two  spaces", RefuseInvalid},
+		{"Ünïcode", RefuseInvalid},
+		{"...", RefuseInvalid},
+	}
+
+	for _, tt := range tests {
+		if got := checkDisplayName(tt.name); got != tt.want {
+			t.Errorf("checkDisplayName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBio(t *testing.T) {
+	tests := []struct {
+		bio  string
+		want string
+	}{
+		{"", ""},
+		{"I'm a therapist from Scunthorpe", ""},
+		{"Badminton and retro shooters", ""},
+		{"Nazim, 34, Staffordshire", ""},
+		{"this game is shit", RefuseProfanity},
+		{"total 5h1tshow", RefuseProfanity},
+		{"the rapist", RefuseProfanity},
+	}
+
+	for _, tt := range tests {
+		if got := checkBio(tt.bio); got != tt.want {
+			t.Errorf("checkBio(%q) = %q, want %q", tt.bio, got, tt.want)
+		}
+	}
+}
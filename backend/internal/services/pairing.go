@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Errors returned when redeeming a pairing code
+var (
+	ErrInvalidPairingCode     = errors.New("invalid or expired pairing code")
+	ErrTooManyPairingAttempts = errors.New("too many pairing attempts")
+)
+
+// pairingCodeAlphabet leaves out letters and digits that are easily confused
+// when read off one screen and typed into another (0/O and 1/I). Its 32
+// characters make each of a code's bytes an unbiased pick.
+const pairingCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const (
+	pairingCodeLength = 6
+	pairingCodeTTL    = 5 * time.Minute
+
+	// An IP address may fail to redeem maxPairingAttempts codes per
+	// pairingAttemptWindow before it is locked out for the rest of it
+	maxPairingAttempts   = 5
+	pairingAttemptWindow = 15 * time.Minute
+
+	// A code is burned once maxPairingCodeMisses redemptions of other codes
+	// have failed, from any address, since it was issued. This bounds the
+	// guesses spread over many addresses that could land on it.
+	maxPairingCodeMisses = 1000
+)
+
+// pairingMissesKey counts every failed redemption, from any address
+const pairingMissesKey = "pairing:misses"
+
+// countAttemptScript counts an attempt against a window that starts with the
+// first attempt, in one step so concurrent attempts cannot slip past the
+// limit or leave the count without an expiry. Returns the count. The tests
+// run it on miniredis's Lua interpreter; it uses only INCR and PEXPIRE, which
+// behave the same on Redis.
+//
+// KEYS[1] counter; ARGV[1] window in milliseconds
+var countAttemptScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// CreatePairingCode issues a short-lived code another device can redeem to
+// join the caller's player. A player has at most one live code; issuing a
+// new one cancels the last.
+func (p *PlayerService) CreatePairingCode(ctx context.Context, playerID uuid.UUID) (*models.PairingCodeResponse, error) {
+	// Cancel the player's previous code
+	if previous, err := p.redis.Get(ctx, playerPairingKey(playerID)).Result(); err == nil {
+		p.redis.Del(ctx, pairingCodeKey(previous))
+	}
+
+	// The code holds its player and the number of failed redemptions so far
+	misses, err := p.redis.Get(ctx, pairingMissesKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read pairing misses: %w", err)
+	}
+	value := pairingCodeValue(playerID, misses)
+
+	// Retry the rare collision with another player's live code
+	for i := 0; i < 3; i++ {
+		code, err := generatePairingCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate pairing code: %w", err)
+		}
+
+		stored, err := p.redis.SetNX(ctx, pairingCodeKey(code), value, pairingCodeTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to store pairing code: %w", err)
+		}
+		if !stored {
+			continue
+		}
+		p.redis.Set(ctx, playerPairingKey(playerID), code, pairingCodeTTL)
+
+		return &models.PairingCodeResponse{
+			Code:      code,
+			ExpiresAt: time.Now().Add(pairingCodeTTL),
+		}, nil
+	}
+
+	return nil, errors.New("failed to generate an unused pairing code")
+}
+
+// RedeemPairingCode opens a new session for the player a pairing code was
+// issued to. Codes work once. Each IP address gets a limited number of
+// failed attempts, and each code outlives a limited number of failures from
+// any address, so codes cannot be guessed.
+func (p *PlayerService) RedeemPairingCode(ctx context.Context, code, ipAddress, userAgent string) (*models.SessionResponse, error) {
+	// Count the attempt before checking it, and give it back if it succeeds
+	attemptsKey := pairingAttemptsKey(ipAddress)
+	attempts, err := countAttemptScript.Run(ctx, p.redis, []string{attemptsKey}, pairingAttemptWindow.Milliseconds()).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pairing attempt: %w", err)
+	}
+	if attempts > maxPairingAttempts {
+		return nil, ErrTooManyPairingAttempts
+	}
+
+	code = normalizePairingCode(code)
+	stored, err := p.redis.GetDel(ctx, pairingCodeKey(code)).Result()
+	if errors.Is(err, redis.Nil) {
+		p.redis.Incr(ctx, pairingMissesKey)
+		return nil, ErrInvalidPairingCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem pairing code: %w", err)
+	}
+
+	playerID, issued, err := parsePairingCodeValue(stored)
+	if err != nil {
+		return nil, ErrInvalidPairingCode
+	}
+	p.redis.Del(ctx, playerPairingKey(playerID))
+
+	// A code that has seen too many failures may have been guessed
+	misses, err := p.redis.Get(ctx, pairingMissesKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read pairing misses: %w", err)
+	}
+	if pairingCodeBurned(issued, misses) {
+		return nil, ErrInvalidPairingCode
+	}
+
+	p.redis.Decr(ctx, attemptsKey)
+
+	return p.sessions.startSession(ctx, &playerID, ipAddress, userAgent)
+}
+
+// pairingCodeValue is what a pairing code's key holds: the player it was
+// issued to and the count of failed redemptions when it was issued
+func pairingCodeValue(playerID uuid.UUID, misses int64) string {
+	return fmt.Sprintf("%s:%d", playerID.String(), misses)
+}
+
+// parsePairingCodeValue reads back a value made by pairingCodeValue
+func parsePairingCodeValue(value string) (uuid.UUID, int64, error) {
+	owner, misses, _ := strings.Cut(value, ":")
+	playerID, err := uuid.Parse(owner)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	issued, err := strconv.ParseInt(misses, 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	return playerID, issued, nil
+}
+
+// pairingCodeBurned reports whether a code issued when the failed
+// redemption count was issued has outlived too many failures to trust
+func pairingCodeBurned(issued, misses int64) bool {
+	return misses-issued >= maxPairingCodeMisses
+}
+
+// generatePairingCode returns a random code drawn from pairingCodeAlphabet
+func generatePairingCode() (string, error) {
+	bytes := make([]byte, pairingCodeLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, pairingCodeLength)
+	for i, b := range bytes {
+		code[i] = pairingCodeAlphabet[int(b)%len(pairingCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// normalizePairingCode accepts codes typed in lowercase or with spaces and dashes
+func normalizePairingCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// pairingCodeKey returns the key holding the player a pairing code was issued to
+func pairingCodeKey(code string) string {
+	return fmt.Sprintf("pairing:code:%s", code)
+}
+
+// playerPairingKey returns the key holding a player's live pairing code
+func playerPairingKey(playerID uuid.UUID) string {
+	return fmt.Sprintf("pairing:player:%s", playerID.String())
+}
+
+// pairingAttemptsKey returns the key counting an IP address's failed attempts
+func pairingAttemptsKey(ipAddress string) string {
+	return fmt.Sprintf("pairing:attempts:%s", ipAddress)
+}
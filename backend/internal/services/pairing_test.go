@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newPairingTestService returns a player service backed by an in-memory Redis
+func newPairingTestService(t *testing.T) (*PlayerService, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &PlayerService{redis: client}, server
+}
+
+func TestGeneratePairingCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		code, err := generatePairingCode()
+		if err != nil {
+			t.Fatalf("generatePairingCode: %v", err)
+		}
+		if len(code) != pairingCodeLength {
+			t.Fatalf("code %q has length %d, want %d", code, len(code), pairingCodeLength)
+		}
+		for _, r := range code {
+			if !strings.ContainsRune(pairingCodeAlphabet, r) {
+				t.Fatalf("code %q contains %q, outside the alphabet", code, r)
+			}
+		}
+		if normalizePairingCode(code) != code {
+			t.Fatalf("code %q is not already normalized", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 195 {
+		t.Errorf("only %d distinct codes in 200", len(seen))
+	}
+
+	if len(pairingCodeAlphabet) != 32 || strings.ContainsAny(pairingCodeAlphabet, "01IO") {
+		t.Errorf("alphabet %q must be 32 characters without 0, 1, I or O", pairingCodeAlphabet)
+	}
+}
+
+func TestNormalizePairingCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"ABC234", "ABC234"},
+		{"abc234", "ABC234"},
+		{"abc-234", "ABC234"},
+		{"ABC 234", "ABC234"},
+		{" a-b c-2 3 4 ", "ABC234"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizePairingCode(tt.code); got != tt.want {
+			t.Errorf("normalizePairingCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestPairingCodeValue(t *testing.T) {
+	playerID := uuid.MustParse("6f1c2a9e-3b4d-4e5f-8a7b-9c0d1e2f3a4b")
+
+	gotID, gotMisses, err := parsePairingCodeValue(pairingCodeValue(playerID, 4321))
+	if err != nil || gotID != playerID || gotMisses != 4321 {
+		t.Errorf("round trip = %v, %d, %v, want %v, 4321", gotID, gotMisses, err, playerID)
+	}
+
+	for _, value := range []string{"", playerID.String(), playerID.String() + ":", "not-a-player:12", playerID.String() + ":x"} {
+		if _, _, err := parsePairingCodeValue(value); err == nil {
+			t.Errorf("parsePairingCodeValue(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestPairingCodeBurned(t *testing.T) {
+	tests := []struct {
+		issued, misses int64
+		want           bool
+	}{
+		{0, 0, false},
+		{0, maxPairingCodeMisses - 1, false},
+		{0, maxPairingCodeMisses, true},
+		{500, 500 + maxPairingCodeMisses - 1, false},
+		{500, 500 + maxPairingCodeMisses, true},
+	}
+
+	for _, tt := range tests {
+		if got := pairingCodeBurned(tt.issued, tt.misses); got != tt.want {
+			t.Errorf("pairingCodeBurned(%d, %d) = %v, want %v", tt.issued, tt.misses, got, tt.want)
+		}
+	}
+}
+
+func TestCountAttemptScript(t *testing.T) {
+	p, server := newPairingTestService(t)
+	ctx := context.Background()
+	window := time.Minute
+
+	for want := 1; want <= 3; want++ {
+		count, err := countAttemptScript.Run(ctx, p.redis, []string{"attempts"}, window.Milliseconds()).Int()
+		if err != nil {
+			t.Fatalf("running script: %v", err)
+		}
+		if count != want {
+			t.Errorf("count = %d, want %d", count, want)
+		}
+		// The window starts with the first attempt; later ones do not extend it
+		server.FastForward(10 * time.Second)
+	}
+	if ttl := server.TTL("attempts"); ttl != window-30*time.Second {
+		t.Errorf("TTL = %v, want %v", ttl, window-30*time.Second)
+	}
+
+	server.FastForward(window)
+	count, err := countAttemptScript.Run(ctx, p.redis, []string{"attempts"}, window.Milliseconds()).Int()
+	if err != nil || count != 1 {
+		t.Errorf("count after the window = %d, %v, want 1", count, err)
+	}
+}
+
+func TestRedeemPairingCodeLimitsAttempts(t *testing.T) {
+	p, server := newPairingTestService(t)
+	ctx := context.Background()
+
+	for i := 0; i < maxPairingAttempts; i++ {
+		if _, err := p.RedeemPairingCode(ctx, "AAAAAA", "203.0.113.7", ""); !errors.Is(err, ErrInvalidPairingCode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidPairingCode", i+1, err)
+		}
+	}
+	if _, err := p.RedeemPairingCode(ctx, "AAAAAA", "203.0.113.7", ""); !errors.Is(err, ErrTooManyPairingAttempts) {
+		t.Errorf("err = %v, want ErrTooManyPairingAttempts", err)
+	}
+
+	// Other addresses are unaffected, and every miss is counted globally
+	if _, err := p.RedeemPairingCode(ctx, "AAAAAA", "198.51.100.2", ""); !errors.Is(err, ErrInvalidPairingCode) {
+		t.Errorf("other address: err = %v, want ErrInvalidPairingCode", err)
+	}
+	if misses, _ := server.Get(pairingMissesKey); misses != "6" {
+		t.Errorf("misses = %s, want 6", misses)
+	}
+
+	server.FastForward(pairingAttemptWindow)
+	if _, err := p.RedeemPairingCode(ctx, "AAAAAA", "203.0.113.7", ""); !errors.Is(err, ErrInvalidPairingCode) {
+		t.Errorf("after the window: err = %v, want ErrInvalidPairingCode", err)
+	}
+}
+
+func TestRedeemPairingCodeBurnsGuessedCodes(t *testing.T) {
+	p, server := newPairingTestService(t)
+	ctx := context.Background()
+	playerID := uuid.New()
+
+	server.Set(pairingMissesKey, "10")
+	code, err := p.CreatePairingCode(ctx, playerID)
+	if err != nil {
+		t.Fatalf("CreatePairingCode: %v", err)
+	}
+	if value, _ := server.Get(pairingCodeKey(code.Code)); value != pairingCodeValue(playerID, 10) {
+		t.Fatalf("stored %q, want the player and 10 misses", value)
+	}
+
+	// Enough failures since the code was issued that it may have been guessed
+	server.Set(pairingMissesKey, "1010")
+	if _, err := p.RedeemPairingCode(ctx, strings.ToLower(code.Code), "203.0.113.7", ""); !errors.Is(err, ErrInvalidPairingCode) {
+		t.Errorf("err = %v, want ErrInvalidPairingCode", err)
+	}
+	if server.Exists(pairingCodeKey(code.Code)) || server.Exists(playerPairingKey(playerID)) {
+		t.Error("burned code was not deleted")
+	}
+}
+
+func TestCreatePairingCodeCancelsPrevious(t *testing.T) {
+	p, server := newPairingTestService(t)
+	ctx := context.Background()
+	playerID := uuid.New()
+
+	first, err := p.CreatePairingCode(ctx, playerID)
+	if err != nil {
+		t.Fatalf("CreatePairingCode: %v", err)
+	}
+	second, err := p.CreatePairingCode(ctx, playerID)
+	if err != nil {
+		t.Fatalf("CreatePairingCode: %v", err)
+	}
+
+	if first.Code != second.Code && server.Exists(pairingCodeKey(first.Code)) {
+		t.Error("previous code still live")
+	}
+	if live, _ := server.Get(playerPairingKey(playerID)); live != second.Code {
+		t.Errorf("live code = %q, want %q", live, second.Code)
+	}
+	if ttl := server.TTL(pairingCodeKey(second.Code)); ttl != pairingCodeTTL {
+		t.Errorf("TTL = %v, want %v", ttl, pairingCodeTTL)
+	}
+}
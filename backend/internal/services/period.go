@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"retro-games-backend/internal/models"
+)
+
+// periodRetention is how long a closed period's sorted set is kept in Redis
+// after the period ends; its final standings live in leaderboard_archives
+const periodRetention = 24 * time.Hour
+
+// endOfTime bounds open-ended queries on the all-time board
+var endOfTime = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// board identifies a single ranked leaderboard: a game over a period window,
+// optionally narrowed to one variant. Start and End are zero for the
+// all-time board. Times are UTC, matching the wall clock stored in
+// scores.achieved_at; connections run with TimeZone set to UTC so that holds
+// whatever the database server's own zone.
+type board struct {
+	GameID    string
+	Variant   models.ScoreVariant
+	Mode      models.LeaderboardMode
+	Direction models.ScoreDirection
+	Period    models.LeaderboardPeriod
+	PeriodKey string
+	Start     time.Time
+	End       time.Time
+}
+
+// sign converts between raw scores and the board's descending sort key
+func (b board) sign() int {
+	return b.Direction.Sign()
+}
+
+// key returns the sorted set key for the board. Variant boards qualify the
+// game as <game>/<gameMode>/<difficulty>, with * for either filter left open.
+func (b board) key() string {
+	game := b.GameID
+	if b.Variant != (models.ScoreVariant{}) {
+		game = fmt.Sprintf("%s/%s/%s", b.GameID, anyIfEmpty(b.Variant.GameMode), anyIfEmpty(b.Variant.Difficulty))
+	}
+	if b.Period == models.PeriodAllTime {
+		return fmt.Sprintf("ranking:%s:%s", game, b.Mode)
+	}
+	return fmt.Sprintf("ranking:%s:%s:%s:%s", game, b.Mode, b.Period, b.PeriodKey)
+}
+
+// anyIfEmpty stands in for an open variant filter in a key
+func anyIfEmpty(value string) string {
+	if value == "" {
+		return "*"
+	}
+	return value
+}
+
+// playersKey maps each player to their member on a best-per-player board
+func (b board) playersKey() string {
+	return b.key() + ":players"
+}
+
+// builtKey marks the board's sorted set as populated, so an empty set can be
+// told apart from one lost to a Redis flush
+func (b board) builtKey() string {
+	return b.key() + ":built"
+}
+
+// bounds returns the board's [start, end) window for querying scores,
+// substituting open-ended limits for the all-time board
+func (b board) bounds() (time.Time, time.Time) {
+	if b.Period == models.PeriodAllTime {
+		return time.Time{}, endOfTime
+	}
+	return b.Start, b.End
+}
+
+// expiresAt returns when the board's Redis keys may be dropped, or the zero
+// time if they never expire
+func (b board) expiresAt() time.Time {
+	if b.Period == models.PeriodAllTime {
+		return time.Time{}
+	}
+	return b.End.Add(periodRetention)
+}
+
+// retired reports whether a closed period's board is past its retention and
+// no longer kept in Redis
+func (b board) retired(now time.Time) bool {
+	expiresAt := b.expiresAt()
+	return !expiresAt.IsZero() && !expiresAt.After(now)
+}
+
+// periodClock computes leaderboard period boundaries in a fixed time zone
+type periodClock struct {
+	loc *time.Location
+}
+
+// newPeriodClock creates a period clock, defaulting to UTC
+func newPeriodClock(loc *time.Location) periodClock {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return periodClock{loc: loc}
+}
+
+// board returns the board for a game's period containing the given instant
+func (p periodClock) board(gameID string, mode models.LeaderboardMode, period models.LeaderboardPeriod, at time.Time) board {
+	b := board{GameID: gameID, Mode: mode, Period: period}
+	if period == models.PeriodAllTime {
+		return b
+	}
+
+	local := at.In(p.loc)
+	var start, end time.Time
+	switch period {
+	case models.PeriodDaily:
+		start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, p.loc)
+		end = start.AddDate(0, 0, 1)
+		b.PeriodKey = start.Format("2006-01-02")
+	case models.PeriodWeekly:
+		// Weeks start on Monday, following ISO 8601
+		offset := (int(local.Weekday()) + 6) % 7
+		start = time.Date(local.Year(), local.Month(), local.Day()-offset, 0, 0, 0, 0, p.loc)
+		end = start.AddDate(0, 0, 7)
+		year, week := start.ISOWeek()
+		b.PeriodKey = fmt.Sprintf("%04d-W%02d", year, week)
+	case models.PeriodMonthly:
+		start = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, p.loc)
+		end = start.AddDate(0, 1, 0)
+		b.PeriodKey = start.Format("2006-01")
+	}
+
+	b.Start = start.UTC()
+	b.End = end.UTC()
+	return b
+}
+
+// current returns the board for a game's period containing now
+func (p periodClock) current(gameID string, mode models.LeaderboardMode, period models.LeaderboardPeriod) board {
+	return p.board(gameID, mode, period, time.Now())
+}
+
+// boardsFor returns every board of the given variants that a game's score
+// achieved at the given instant counts towards
+func (p periodClock) boardsFor(game models.Game, variants []models.ScoreVariant, achievedAt time.Time) []board {
+	var boards []board
+	for _, variant := range variants {
+		for _, mode := range []models.LeaderboardMode{models.ModeBestPerPlayer, models.ModeAllRuns} {
+			for _, period := range append([]models.LeaderboardPeriod{models.PeriodAllTime}, models.ClosablePeriods...) {
+				b := p.board(game.ID, mode, period, achievedAt)
+				b.Variant = variant
+				b.Direction = game.Scoring.Direction
+				boards = append(boards, b)
+			}
+		}
+	}
+	return boards
+}
+
+// scoreVariants returns the variant boards a score with the given metadata
+// is ranked on: the unfiltered board plus one per filter it can match
+func scoreVariants(meta models.ScoreMetadata) []models.ScoreVariant {
+	variants := []models.ScoreVariant{{}}
+	if meta.Mode != "" {
+		variants = append(variants, models.ScoreVariant{GameMode: meta.Mode})
+	}
+	if meta.Difficulty != "" {
+		variants = append(variants, models.ScoreVariant{Difficulty: meta.Difficulty})
+	}
+	if meta.Mode != "" && meta.Difficulty != "" {
+		variants = append(variants, meta.Variant())
+	}
+	return variants
+}
+
+// gameVariants returns every variant board a game can have
+func gameVariants(game models.Game) []models.ScoreVariant {
+	modes := append([]string{""}, game.Metadata.Modes...)
+	difficulties := append([]string{""}, game.Metadata.Difficulties...)
+
+	var variants []models.ScoreVariant
+	for _, mode := range modes {
+		for _, difficulty := range difficulties {
+			variants = append(variants, models.ScoreVariant{GameMode: mode, Difficulty: difficulty})
+		}
+	}
+	return variants
+}
+
+// closed returns the most recent closed windows of a period, newest first
+func (p periodClock) closed(period models.LeaderboardPeriod, count int) []board {
+	var boards []board
+	current := p.current("", models.ModeBestPerPlayer, period)
+	for i := 0; i < count; i++ {
+		previous := p.board("", models.ModeBestPerPlayer, period, current.Start.Add(-time.Nanosecond))
+		boards = append(boards, previous)
+		current = previous
+	}
+	return boards
+}
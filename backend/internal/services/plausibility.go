@@ -0,0 +1,48 @@
+package services
+
+import (
+	"time"
+
+	"retro-games-backend/internal/models"
+)
+
+// Reasons a score can be flagged as implausible
+const (
+	FlagAboveMaxScore   = "above_max_score"
+	FlagPointsPerSecond = "points_per_second"
+	FlagGranularity     = "granularity"
+	FlagDuration        = "duration"
+)
+
+// durationSlack allows for a client's run timer running slightly ahead of
+// the server's clock
+const durationSlack = 2 * time.Second
+
+// checkPlausibility evaluates a score against its game's rules and returns
+// the reason it should be flagged, or an empty string if it is plausible.
+// elapsed is the time between the run starting and the score being submitted.
+func checkPlausibility(game models.Game, score int, meta models.ScoreMetadata, elapsed time.Duration) string {
+	rules := game.Rules
+
+	if rules.MaxScore > 0 && score > rules.MaxScore {
+		return FlagAboveMaxScore
+	}
+
+	if rules.Granularity > 1 && score%rules.Granularity != 0 {
+		return FlagGranularity
+	}
+
+	// A points rate only makes sense for games where more is better
+	if rules.MaxPointsPerSecond > 0 && game.Scoring.Direction == models.ScoreHigherIsBetter {
+		if float64(score) > rules.MaxPointsPerSecond*elapsed.Seconds() {
+			return FlagPointsPerSecond
+		}
+	}
+
+	// A run cannot last longer than its ticket has been open
+	if meta.DurationMs != nil && time.Duration(*meta.DurationMs)*time.Millisecond > elapsed+durationSlack {
+		return FlagDuration
+	}
+
+	return ""
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"retro-games-backend/internal/models"
+)
+
+func TestCheckPlausibility(t *testing.T) {
+	tetris := models.Game{
+		ID:      "tetris",
+		Scoring: models.Scoring{Direction: models.ScoreHigherIsBetter},
+		Rules:   models.ScoreRules{MaxPointsPerSecond: 1000, Granularity: 1},
+	}
+	pong := models.Game{
+		ID:      "pong",
+		Scoring: models.Scoring{Direction: models.ScoreHigherIsBetter},
+		Rules:   models.ScoreRules{MaxScore: 100000, Granularity: 10},
+	}
+	timed := models.Game{
+		ID:      "timed",
+		Scoring: models.Scoring{Direction: models.ScoreLowerIsBetter},
+		Rules:   models.ScoreRules{MaxPointsPerSecond: 1, Granularity: 1},
+	}
+	durationMs := func(ms int) models.ScoreMetadata {
+		return models.ScoreMetadata{DurationMs: &ms}
+	}
+
+	tests := []struct {
+		name    string
+		game    models.Game
+		score   int
+		meta    models.ScoreMetadata
+		elapsed time.Duration
+		want    string
+	}{
+		{"plausible", tetris, 50000, models.ScoreMetadata{}, 2 * time.Minute, ""},
+		{"at the points rate", tetris, 60000, models.ScoreMetadata{}, time.Minute, ""},
+		{"above the points rate", tetris, 60001, models.ScoreMetadata{}, time.Minute, FlagPointsPerSecond},
+		{"points rate ignored for lower is better", timed, 60000, models.ScoreMetadata{}, time.Second, ""},
+		{"at max score", pong, 100000, models.ScoreMetadata{}, time.Hour, ""},
+		{"above max score", pong, 100010, models.ScoreMetadata{}, time.Hour, FlagAboveMaxScore},
+		{"off granularity", pong, 105, models.ScoreMetadata{}, time.Minute, FlagGranularity},
+		{"duration within slack", tetris, 100, durationMs(61000), time.Minute, ""},
+		{"duration beyond the ticket", tetris, 100, durationMs(63000), time.Minute, FlagDuration},
+		{"max score checked first", pong, 100005, durationMs(1 << 30), time.Second, FlagAboveMaxScore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPlausibility(tt.game, tt.score, tt.meta, tt.elapsed); got != tt.want {
+				t.Errorf("checkPlausibility(%d, %v) = %q, want %q", tt.score, tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/argon2"
+)
+
+// Errors returned when registering or logging in
+var (
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrUsernameTaken      = errors.New("username taken")
+	ErrAlreadyRegistered  = errors.New("player already registered")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// usernamePattern limits usernames to 3-32 letters, digits, '_' and '-'.
+// Usernames are unique regardless of case.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+// Argon2id parameters for new password hashes. Stored hashes carry their
+// own parameters, so these can be raised without invalidating old ones.
+const (
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// dummyPasswordHash is verified against when logging in to an unknown
+// username, so the response takes as long as for a wrong password
+var dummyPasswordHash, _ = hashPassword("not a password")
+
+// PlayerService handles player accounts
+type PlayerService struct {
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	sessions *SessionService
+}
+
+// NewPlayerService creates a new player service
+func NewPlayerService(db *pgxpool.Pool, redis *redis.Client, sessions *SessionService) *PlayerService {
+	return &PlayerService{
+		db:       db,
+		redis:    redis,
+		sessions: sessions,
+	}
+}
+
+// GetPlayer describes a player
+func (p *PlayerService) GetPlayer(ctx context.Context, playerID uuid.UUID) (*models.PlayerInfo, error) {
+	query := `
+		SELECT COALESCE(username, ''), created_at, registered_at
+		FROM players
+		WHERE id = $1
+	`
+
+	info := &models.PlayerInfo{PlayerID: playerID}
+	err := p.db.QueryRow(ctx, query, playerID).Scan(&info.Username, &info.CreatedAt, &info.RegisteredAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player: %w", err)
+	}
+
+	return info, nil
+}
+
+// Register gives the caller's anonymous player a username and password, so
+// it can be logged in to from other devices. Its scores, personal bests and
+// saves carry over unchanged.
+func (p *PlayerService) Register(ctx context.Context, playerID uuid.UUID, req models.RegisterRequest) (*models.PlayerInfo, error) {
+	if !usernamePattern.MatchString(req.Username) {
+		return nil, ErrInvalidUsername
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	query := `
+		UPDATE players
+		SET username = $2, password_hash = $3, registered_at = LOCALTIMESTAMP
+		WHERE id = $1 AND username IS NULL
+		RETURNING created_at, registered_at
+	`
+
+	info := &models.PlayerInfo{PlayerID: playerID, Username: req.Username}
+	err = p.db.QueryRow(ctx, query, playerID, req.Username, hash).Scan(&info.CreatedAt, &info.RegisteredAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrUsernameTaken
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlreadyRegistered
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to register player: %w", err)
+	}
+
+	return info, nil
+}
+
+// Login checks a username and password and opens a new session for the
+// player they belong to. The caller's other sessions are unaffected.
+func (p *PlayerService) Login(ctx context.Context, req models.LoginRequest, ipAddress, userAgent string) (*models.SessionResponse, error) {
+	query := `
+		SELECT id, password_hash
+		FROM players
+		WHERE LOWER(username) = LOWER($1) AND password_hash IS NOT NULL
+	`
+
+	var playerID uuid.UUID
+	var hash string
+	err := p.db.QueryRow(ctx, query, req.Username).Scan(&playerID, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		verifyPassword(dummyPasswordHash, req.Password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up player: %w", err)
+	}
+
+	if !verifyPassword(hash, req.Password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return p.sessions.startSession(ctx, &playerID, ipAddress, userAgent)
+}
+
+// hashPassword hashes a password with argon2id into the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyPassword reports whether a password matches an argon2id hash made
+// by hashPassword, using the parameters stored in the hash
+func verifyPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	// argon2 panics on parameters below these
+	if iterations < 1 || threads < 1 || memory < 8*uint32(threads) {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	hash, err := hashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+
+	wantPrefix := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$", argon2.Version, argonMemory, argonTime, argonThreads)
+	if !strings.HasPrefix(hash, wantPrefix) {
+		t.Errorf("hash %q does not start with %q", hash, wantPrefix)
+	}
+
+	if !verifyPassword(hash, "correct horse battery staple") {
+		t.Error("password does not verify against its own hash")
+	}
+	if verifyPassword(hash, "correct horse battery stapler") {
+		t.Error("wrong password verifies")
+	}
+	if verifyPassword(hash, "") {
+		t.Error("empty password verifies")
+	}
+
+	// Each hash gets its own salt
+	again, err := hashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	if again == hash {
+		t.Error("two hashes of the same password are identical")
+	}
+}
+
+func TestVerifyPasswordParameters(t *testing.T) {
+	// A hash made with other parameters verifies with the ones it records
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("hunter2"), salt, 2, 16, 1, 16)
+	hash := fmt.Sprintf("$argon2id$v=%d$m=16,t=2,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	if !verifyPassword(hash, "hunter2") {
+		t.Error("password does not verify against a hash with its own parameters")
+	}
+	if verifyPassword(hash, "hunter3") {
+		t.Error("wrong password verifies")
+	}
+}
+
+func TestVerifyPasswordMalformed(t *testing.T) {
+	valid, err := hashPassword("hunter2")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	parts := strings.Split(valid, "$")
+	with := func(i int, value string) string {
+		changed := append([]string(nil), parts...)
+		changed[i] = value
+		return strings.Join(changed, "$")
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"bcrypt", "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"},
+		{"argon2i", with(1, "argon2i")},
+		{"too few parts", strings.Join(parts[:5], "$")},
+		{"too many parts", valid + "$extra"},
+		{"other version", with(2, "v=16")},
+		{"garbled version", with(2, "version")},
+		{"garbled parameters", with(3, "m=x,t=1,p=4")},
+		{"zero iterations", with(3, "m=65536,t=0,p=4")},
+		{"zero threads", with(3, "m=65536,t=1,p=0")},
+		{"too little memory", with(3, "m=1,t=1,p=4")},
+		{"salt not base64", with(4, "!!!")},
+		{"key not base64", with(5, "!!!")},
+		{"empty key", with(5, "")},
+		{"truncated key", with(5, parts[5][:10])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if verifyPassword(tt.hash, "hunter2") {
+				t.Errorf("verifyPassword(%q) = true, want false", tt.hash)
+			}
+		})
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	if dummyPasswordHash == "" {
+		t.Fatal("dummyPasswordHash is empty")
+	}
+	// Logins to unknown usernames verify against it, and must always fail
+	if verifyPassword(dummyPasswordHash, "") || verifyPassword(dummyPasswordHash, "hunter2") {
+		t.Error("dummyPasswordHash verifies a password")
+	}
+	if !strings.HasPrefix(dummyPasswordHash, "$argon2id$") {
+		t.Errorf("dummyPasswordHash %q is not an argon2id hash, so it would not cost the same to check", dummyPasswordHash)
+	}
+}
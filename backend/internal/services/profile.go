@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/text/language"
+)
+
+// ErrProfileNotFound is returned when no profile has a display name
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ProfileRefusedError is returned when a profile field breaks the profile
+// rules. Field is the JSON name of the field and Reason one of the Refuse
+// reasons.
+type ProfileRefusedError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ProfileRefusedError) Error() string {
+	return fmt.Sprintf("profile %s refused: %s", e.Field, e.Reason)
+}
+
+// Avatars are the pixel avatars a profile can choose from, drawn by the
+// frontend from its sprite sheet
+var Avatars = []string{
+	"alien", "astronaut", "cat", "dragon", "frog", "ghost", "heart", "knight",
+	"mushroom", "ninja", "robot", "rocket", "skull", "slime", "star", "ufo",
+	"wizard",
+}
+
+// countryPattern accepts ISO 3166-1 alpha-2 codes in either case
+var countryPattern = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+// ProfileService handles player profiles
+type ProfileService struct {
+	db    *pgxpool.Pool
+	redis *redis.Client
+}
+
+// NewProfileService creates a new profile service
+func NewProfileService(db *pgxpool.Pool, redis *redis.Client) *ProfileService {
+	return &ProfileService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// GetProfile returns a player's profile, empty if they have not set one
+func (p *ProfileService) GetProfile(ctx context.Context, playerID uuid.UUID) (*models.Profile, error) {
+	query := `
+		SELECT COALESCE(display_name, ''), COALESCE(avatar, ''), COALESCE(country, ''),
+		       COALESCE(bio, ''), updated_at
+		FROM player_profiles
+		WHERE player_id = $1
+	`
+
+	profile := &models.Profile{PlayerID: playerID}
+	err := p.db.QueryRow(ctx, query, playerID).Scan(
+		&profile.DisplayName, &profile.Avatar, &profile.Country, &profile.Bio, &profile.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile replaces a player's profile. Display names must pass the
+// profanity and impersonation filters, must not have been blocked by an
+// admin, and must be unique once folded by nameKey; they also may not fold
+// to another player's username. Bios are checked for profanity.
+func (p *ProfileService) UpdateProfile(ctx context.Context, playerID uuid.UUID, req models.UpdateProfileRequest) (*models.Profile, error) {
+	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	req.Bio = strings.TrimSpace(req.Bio)
+
+	var key string
+	if req.DisplayName != "" {
+		if reason := checkDisplayName(req.DisplayName); reason != "" {
+			return nil, &ProfileRefusedError{Field: "display_name", Reason: reason}
+		}
+		key = nameKey(req.DisplayName)
+
+		reason, err := p.checkNameAvailable(ctx, playerID, key)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			return nil, &ProfileRefusedError{Field: "display_name", Reason: reason}
+		}
+	}
+
+	if req.Avatar != "" && !isAvatar(req.Avatar) {
+		return nil, &ProfileRefusedError{Field: "avatar", Reason: RefuseInvalid}
+	}
+
+	if req.Country != "" {
+		country, ok := normalizeCountry(req.Country)
+		if !ok {
+			return nil, &ProfileRefusedError{Field: "country", Reason: RefuseInvalid}
+		}
+		req.Country = country
+	}
+
+	if reason := checkBio(req.Bio); reason != "" {
+		return nil, &ProfileRefusedError{Field: "bio", Reason: reason}
+	}
+
+	query := `
+		INSERT INTO player_profiles (player_id, display_name, name_key, avatar, country, bio, updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), LOCALTIMESTAMP)
+		ON CONFLICT (player_id) DO UPDATE
+		SET display_name = EXCLUDED.display_name, name_key = EXCLUDED.name_key,
+		    avatar = EXCLUDED.avatar, country = EXCLUDED.country, bio = EXCLUDED.bio,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+
+	profile := &models.Profile{
+		PlayerID:    playerID,
+		DisplayName: req.DisplayName,
+		Avatar:      req.Avatar,
+		Country:     req.Country,
+		Bio:         req.Bio,
+	}
+	var updatedAt time.Time
+	err := p.db.QueryRow(ctx, query, playerID, req.DisplayName, key, req.Avatar, req.Country, req.Bio).Scan(&updatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// Another player took the name since it was checked
+		return nil, &ProfileRefusedError{Field: "display_name", Reason: RefuseTaken}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	profile.UpdatedAt = &updatedAt
+
+	// Leaderboards show profiles, so cached global rankings are stale
+	p.redis.Incr(ctx, globalLeaderboardVersionKey)
+
+	return profile, nil
+}
+
+// ResetDisplayName clears an offending display name and its bio, and blocks
+// the name so no player can take it again. It returns the profile as reset.
+func (p *ProfileService) ResetDisplayName(ctx context.Context, displayName string) (*models.Profile, error) {
+	key := nameKey(displayName)
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE player_profiles
+		SET display_name = NULL, name_key = NULL, bio = NULL, moderated_at = LOCALTIMESTAMP
+		WHERE name_key = $1
+		RETURNING player_id, COALESCE(avatar, ''), COALESCE(country, ''), moderated_at
+	`
+
+	var profile models.Profile
+	var moderatedAt time.Time
+	err = tx.QueryRow(ctx, query, key).Scan(&profile.PlayerID, &profile.Avatar, &profile.Country, &moderatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset profile: %w", err)
+	}
+	profile.UpdatedAt = &moderatedAt
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO blocked_display_names (name_key, display_name)
+		VALUES ($1, $2)
+		ON CONFLICT (name_key) DO NOTHING
+	`, key, displayName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to block display name: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reset profile: %w", err)
+	}
+
+	p.redis.Incr(ctx, globalLeaderboardVersionKey)
+
+	return &profile, nil
+}
+
+// checkNameAvailable returns the reason a folded display name is not
+// available to a player, or an empty string if it is. Usernames are folded
+// in SQL the same way nameKey folds display names.
+func (p *ProfileService) checkNameAvailable(ctx context.Context, playerID uuid.UUID, key string) (string, error) {
+	query := `
+		SELECT
+		    EXISTS (SELECT 1 FROM blocked_display_names WHERE name_key = $2),
+		    EXISTS (SELECT 1 FROM player_profiles WHERE name_key = $2 AND player_id <> $1),
+		    EXISTS (
+		        SELECT 1 FROM players
+		        WHERE id <> $1 AND username IS NOT NULL
+		          AND regexp_replace(translate(LOWER(username), '0134578@$', 'oieastbas'), '[^a-z0-9]', '', 'g') = $2
+		    )
+	`
+
+	var blocked, taken, impersonates bool
+	err := p.db.QueryRow(ctx, query, playerID, key).Scan(&blocked, &taken, &impersonates)
+	if err != nil {
+		return "", fmt.Errorf("failed to check display name: %w", err)
+	}
+
+	switch {
+	case blocked:
+		return RefuseBlocked, nil
+	case taken:
+		return RefuseTaken, nil
+	case impersonates:
+		return RefuseImpersonation, nil
+	}
+	return "", nil
+}
+
+// isAvatar reports whether an avatar is one of Avatars
+func isAvatar(avatar string) bool {
+	for _, a := range Avatars {
+		if a == avatar {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCountry returns the canonical upper-case code for an ISO 3166-1
+// alpha-2 country code
+func normalizeCountry(code string) (string, bool) {
+	if !countryPattern.MatchString(code) {
+		return "", false
+	}
+	region, err := language.ParseRegion(code)
+	if err != nil || !region.IsCountry() {
+		return "", false
+	}
+	return region.String(), true
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// rebuildBatchSize is the number of members written per pipeline during a rebuild
+const rebuildBatchSize = 1000
+
+// maxMemberMillis is used to invert timestamps so that, among equal scores,
+// the earliest run sorts first in a ZREVRANGE
+const maxMemberMillis int64 = 9999999999999
+
+// keepBestScript records a run on a best-per-player board, replacing the
+// player's previous member only if the new run beats it. Members compare
+// greater for earlier runs, so ties keep the original. Returns the member
+// that now represents the player.
+//
+// KEYS[1] sorted set, KEYS[2] player hash; ARGV[1] player, ARGV[2] member, ARGV[3] score
+var keepBestScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[2], ARGV[1])
+if current then
+	local currentScore = tonumber(redis.call('ZSCORE', KEYS[1], current))
+	local score = tonumber(ARGV[3])
+	if currentScore and (currentScore > score or (currentScore == score and current >= ARGV[2])) then
+		return current
+	end
+	redis.call('ZREM', KEYS[1], current)
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[2])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+return ARGV[2]
+`)
+
+// rankingIndex maintains each game's leaderboards as Redis sorted sets, one
+// per board. Postgres remains the source of truth; any sorted set can be
+// rebuilt from the scores table at any time.
+type rankingIndex struct {
+	db    *pgxpool.Pool
+	redis *redis.Client
+}
+
+// rankedScore is a score row as written to a board. Score is the raw value;
+// it is multiplied by the board's sign before being stored as a sort key.
+type rankedScore struct {
+	ScoreID    uuid.UUID
+	PlayerID   uuid.UUID
+	Score      int
+	AchievedAt time.Time
+}
+
+// rankedMember is a single sorted set member decoded back into a score
+type rankedMember struct {
+	ScoreID uuid.UUID
+	Score   int
+	Rank    int
+}
+
+// newRankingIndex creates a ranking index backed by the given stores
+func newRankingIndex(db *pgxpool.Pool, redis *redis.Client) *rankingIndex {
+	return &rankingIndex{
+		db:    db,
+		redis: redis,
+	}
+}
+
+// rankingMember encodes a score as a sorted set member. The inverted
+// timestamp prefix breaks ties in favour of the earlier run.
+func rankingMember(scoreID uuid.UUID, achievedAt time.Time) string {
+	return fmt.Sprintf("%013d:%s", maxMemberMillis-achievedAt.UnixMilli(), scoreID.String())
+}
+
+// parseRankingMember extracts the score ID from a sorted set member
+func parseRankingMember(member string) (uuid.UUID, error) {
+	_, id, found := strings.Cut(member, ":")
+	if !found {
+		return uuid.Nil, fmt.Errorf("malformed ranking member %q", member)
+	}
+	return uuid.Parse(id)
+}
+
+// Add records a newly inserted score on a board and returns the 1-based rank
+// of the member now representing it: the run itself on an all-runs board, or
+// the player's best run on a best-per-player board
+func (r *rankingIndex) Add(ctx context.Context, b board, score rankedScore) (int, error) {
+	if err := r.ensure(ctx, b); err != nil {
+		return 0, err
+	}
+
+	member, err := r.write(ctx, b.key(), b.playersKey(), b, score)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add score to ranking: %w", err)
+	}
+	r.expire(ctx, b)
+
+	return r.Rank(ctx, b, member)
+}
+
+// Rank returns the 1-based rank of a member, or 0 if it is not ranked
+func (r *rankingIndex) Rank(ctx context.Context, b board, member string) (int, error) {
+	rank, err := r.redis.ZRevRank(ctx, b.key(), member).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rank: %w", err)
+	}
+	return int(rank) + 1, nil
+}
+
+// PlayerMember returns the member representing a player on a best-per-player
+// board, or an empty string if the player has no ranked run
+func (r *rankingIndex) PlayerMember(ctx context.Context, b board, playerID uuid.UUID) (string, error) {
+	if err := r.ensure(ctx, b); err != nil {
+		return "", err
+	}
+
+	member, err := r.redis.HGet(ctx, b.playersKey(), playerID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get player ranking: %w", err)
+	}
+	return member, nil
+}
+
+// Range returns ranked members from offset (0-based) up to limit entries
+func (r *rankingIndex) Range(ctx context.Context, b board, offset, limit int) ([]rankedMember, error) {
+	if err := r.ensure(ctx, b); err != nil {
+		return nil, err
+	}
+
+	results, err := r.redis.ZRevRangeWithScores(ctx, b.key(), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ranking: %w", err)
+	}
+
+	members := make([]rankedMember, 0, len(results))
+	for i, z := range results {
+		scoreID, err := parseRankingMember(z.Member)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, rankedMember{
+			ScoreID: scoreID,
+			Score:   int(z.Score) * b.sign(),
+			Rank:    offset + i + 1,
+		})
+	}
+
+	return members, nil
+}
+
+// Count returns the number of ranked members on a board
+func (r *rankingIndex) Count(ctx context.Context, b board) (int, error) {
+	if err := r.ensure(ctx, b); err != nil {
+		return 0, err
+	}
+
+	count, err := r.redis.ZCard(ctx, b.key()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ranking: %w", err)
+	}
+	return int(count), nil
+}
+
+// ensure rebuilds a board's sorted set from Postgres if it has gone missing
+func (r *rankingIndex) ensure(ctx context.Context, b board) error {
+	built, err := r.redis.Exists(ctx, b.builtKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check ranking: %w", err)
+	}
+	if built == 1 {
+		return nil
+	}
+
+	_, err = r.Rebuild(ctx, b)
+	return err
+}
+
+// Rebuild repopulates a board's sorted set from the scores table and returns
+// the number of members indexed. The set is built under temporary keys and
+// swapped in atomically, then any scores submitted while it was building
+// are replayed so none are lost.
+func (r *rankingIndex) Rebuild(ctx context.Context, b board) (int, error) {
+	var startedAt time.Time
+	err := r.db.QueryRow(ctx, `SELECT LOCALTIMESTAMP - INTERVAL '1 minute'`).Scan(&startedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read database clock: %w", err)
+	}
+
+	key, playersKey := b.key(), b.playersKey()
+	suffix := ":rebuild:" + uuid.NewString()
+	tmpKey, tmpPlayersKey := key+suffix, playersKey+suffix
+
+	count, err := r.load(ctx, tmpKey, tmpPlayersKey, b, time.Time{}, false)
+	if err != nil {
+		r.redis.Del(ctx, tmpKey, tmpPlayersKey)
+		return 0, err
+	}
+
+	pipe := r.redis.TxPipeline()
+	if count == 0 {
+		pipe.Del(ctx, key, playersKey)
+	} else {
+		pipe.Rename(ctx, tmpKey, key)
+		if b.Mode == models.ModeBestPerPlayer {
+			pipe.Rename(ctx, tmpPlayersKey, playersKey)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to swap rebuilt ranking: %w", err)
+	}
+
+	if _, err := r.load(ctx, key, playersKey, b, startedAt, true); err != nil {
+		return 0, err
+	}
+
+	if err := r.redis.Set(ctx, b.builtKey(), time.Now().Unix(), 0).Err(); err != nil {
+		return 0, fmt.Errorf("failed to mark ranking as built: %w", err)
+	}
+	r.expire(ctx, b)
+
+	return count, nil
+}
+
+// expire applies the board's retention to its Redis keys. A board already
+// past it is left alone, as expiring it would drop it at once.
+func (r *rankingIndex) expire(ctx context.Context, b board) {
+	if expiresAt := b.expiresAt(); !expiresAt.IsZero() && !b.retired(time.Now()) {
+		r.redis.ExpireAt(ctx, b.key(), expiresAt)
+		r.redis.ExpireAt(ctx, b.playersKey(), expiresAt)
+		r.redis.ExpireAt(ctx, b.builtKey(), expiresAt)
+	}
+}
+
+// write records a single score on a board's keys and returns the member
+// that now represents it
+func (r *rankingIndex) write(ctx context.Context, key, playersKey string, b board, score rankedScore) (string, error) {
+	member := rankingMember(score.ScoreID, score.AchievedAt)
+	sortKey := score.Score * b.sign()
+	if b.Mode == models.ModeAllRuns {
+		err := r.redis.ZAdd(ctx, key, redis.Z{Score: float64(sortKey), Member: member}).Err()
+		return member, err
+	}
+
+	return keepBestScript.Run(ctx, r.redis,
+		[]string{key, playersKey},
+		score.PlayerID.String(), member, sortKey,
+	).Text()
+}
+
+// load writes every score on a board achieved at or after since into the
+// given keys and returns the number of members written. Best-per-player
+// boards only load each player's best run. When merge is set, runs are
+// merged one at a time against members already present.
+func (r *rankingIndex) load(ctx context.Context, key, playersKey string, b board, since time.Time, merge bool) (int, error) {
+	start, until := b.bounds()
+	if start.After(since) {
+		since = start
+	}
+
+	query := `
+		SELECT id, player_id, score, achieved_at
+		FROM scores
+		WHERE game_id = $1 AND status = 'accepted'
+		  AND achieved_at >= $2 AND achieved_at < $3
+		  AND ($4::text = '' OR game_mode = $4::text)
+		  AND ($5::text = '' OR difficulty = $5::text)
+	`
+	if b.Mode == models.ModeBestPerPlayer {
+		query = `
+			SELECT DISTINCT ON (player_id) id, player_id, score, achieved_at
+			FROM scores
+			WHERE game_id = $1 AND status = 'accepted'
+			  AND achieved_at >= $2 AND achieved_at < $3
+			  AND ($4::text = '' OR game_mode = $4::text)
+			  AND ($5::text = '' OR difficulty = $5::text)
+			ORDER BY player_id, score * $6 DESC, achieved_at ASC
+		`
+	}
+
+	args := []interface{}{b.GameID, since, until, b.Variant.GameMode, b.Variant.Difficulty}
+	if b.Mode == models.ModeBestPerPlayer {
+		args = append(args, b.sign())
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load scores for ranking: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	batch := make([]rankedScore, 0, rebuildBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = batch[:0] }()
+
+		if merge {
+			for _, score := range batch {
+				if _, err := r.write(ctx, key, playersKey, b, score); err != nil {
+					return fmt.Errorf("failed to write ranking batch: %w", err)
+				}
+			}
+			return nil
+		}
+
+		members := make([]redis.Z, len(batch))
+		players := make([]interface{}, 0, len(batch)*2)
+		for i, score := range batch {
+			member := rankingMember(score.ScoreID, score.AchievedAt)
+			members[i] = redis.Z{Score: float64(score.Score * b.sign()), Member: member}
+			players = append(players, score.PlayerID.String(), member)
+		}
+
+		pipe := r.redis.Pipeline()
+		pipe.ZAdd(ctx, key, members...)
+		if b.Mode == models.ModeBestPerPlayer {
+			pipe.HSet(ctx, playersKey, players...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to write ranking batch: %w", err)
+		}
+		return nil
+	}
+
+	for rows.Next() {
+		var score rankedScore
+		if err := rows.Scan(&score.ScoreID, &score.PlayerID, &score.Score, &score.AchievedAt); err != nil {
+			return 0, fmt.Errorf("failed to scan score for ranking: %w", err)
+		}
+
+		batch = append(batch, score)
+		count++
+
+		if len(batch) == rebuildBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to load scores for ranking: %w", err)
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/replay"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrReplayNotFound is returned when a score has no stored replay
+var ErrReplayNotFound = errors.New("replay not found")
+
+// verifyReplay replays a replay-verified game's submitted input from the
+// run's seed and checks it produces the claimed score. A failed check is
+// recorded within tx and returned as a *SubmissionRejectedError.
+func (s *ScoreService) verifyReplay(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, req models.ScoreSubmissionRequest, run redeemedRun, engine replay.Engine) (replay.Result, error) {
+	if req.Replay == nil || run.Seed == nil {
+		return replay.Result{}, s.reject(ctx, tx, sessionID, req, RejectMissingReplay)
+	}
+	if req.Difficulty != req.Replay.Difficulty {
+		return replay.Result{}, s.reject(ctx, tx, sessionID, req, RejectInvalidReplay)
+	}
+
+	result, err := engine.Replay(replayInput(*run.Seed, req.Replay))
+	if err != nil {
+		return replay.Result{}, s.reject(ctx, tx, sessionID, req, RejectInvalidReplay)
+	}
+	if err := engine.Check(result, req.Score, run.Elapsed); err != nil {
+		return replay.Result{}, s.reject(ctx, tx, sessionID, req, RejectReplayMismatch)
+	}
+
+	return result, nil
+}
+
+// storeReplay keeps a verified run's input for later viewing
+func (s *ScoreService) storeReplay(ctx context.Context, tx pgx.Tx, scoreID uuid.UUID, seed int64, input *models.ReplayInput, result replay.Result) error {
+	query := `
+		INSERT INTO score_replays (score_id, seed, difficulty, moves, move_count, solved)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+	`
+
+	_, err := tx.Exec(ctx, query, scoreID, seed, input.Difficulty, input.Moves, result.Moves, result.Solved)
+	if err != nil {
+		return fmt.Errorf("failed to store replay: %w", err)
+	}
+	return nil
+}
+
+// GetReplay returns the stored replay behind a score
+func (s *ScoreService) GetReplay(ctx context.Context, scoreID uuid.UUID) (*models.ReplayResponse, error) {
+	query := `
+		SELECT s.game_id, s.score, s.achieved_at, r.seed, COALESCE(r.difficulty, ''), r.moves, r.move_count, r.solved
+		FROM score_replays r
+		JOIN scores s ON s.id = r.score_id
+		WHERE r.score_id = $1
+	`
+
+	response := &models.ReplayResponse{ScoreID: scoreID}
+	var seed int64
+
+	err := s.db.QueryRow(ctx, query, scoreID).Scan(
+		&response.GameID, &response.Score, &response.AchievedAt, &seed,
+		&response.Difficulty, &response.Moves, &response.MoveCount, &response.Solved,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReplayNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replay: %w", err)
+	}
+	response.Seed = uint32(seed)
+
+	return response, nil
+}
+
+// replayInput converts a submitted replay into engine input
+func replayInput(seed int64, input *models.ReplayInput) replay.Input {
+	return replay.Input{
+		Seed:       uint32(seed),
+		Difficulty: input.Difficulty,
+		Moves:      input.Moves,
+	}
+}
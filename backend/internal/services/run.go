@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/replay"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// runTicketTTL is how long a run ticket can be redeemed after the game starts
+const runTicketTTL = 6 * time.Hour
+
+// offlineSyncWindow is how long after its ticket expires a run played
+// offline can still be synced, as long as it ended before the expiry
+const offlineSyncWindow = 7 * 24 * time.Hour
+
+// maxClockSkew is how far ahead of the server's clock a client-timed run may end
+const maxClockSkew = time.Minute
+
+// Reasons a score submission can be rejected
+const (
+	RejectMissingTicket  = "missing_ticket"
+	RejectUnknownTicket  = "unknown_ticket"
+	RejectTicketMismatch = "ticket_mismatch"
+	RejectTicketUsed     = "ticket_used"
+	RejectTicketExpired  = "ticket_expired"
+	RejectBadSignature   = "bad_signature"
+	RejectTooFast        = "too_fast"
+	RejectBadTimestamp   = "bad_timestamp"
+	RejectMissingReplay  = "missing_replay"
+	RejectInvalidReplay  = "invalid_replay"
+	RejectReplayMismatch = "replay_mismatch"
+)
+
+// SubmissionRejectedError is returned when a score submission fails
+// verification. The submission is recorded in rejected_submissions.
+type SubmissionRejectedError struct {
+	Reason string
+}
+
+func (e *SubmissionRejectedError) Error() string {
+	return fmt.Sprintf("score submission rejected: %s", e.Reason)
+}
+
+// redeemedRun is a run ticket that has passed verification. The run ended
+// at EndedAt, when the ticket was redeemed unless the client timed it.
+type redeemedRun struct {
+	IssuedAt time.Time
+	EndedAt  time.Time
+	Elapsed  time.Duration
+	Seed     *int64
+}
+
+// StartRun issues a single-use run ticket for a game the session is starting
+func (s *ScoreService) StartRun(ctx context.Context, sessionID uuid.UUID, gameID string) (*models.RunTicketResponse, error) {
+	if _, err := s.games.Get(ctx, gameID); err != nil {
+		return nil, err
+	}
+
+	signingKey, err := generateSigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	// Replay-verified games are dealt from a seed the server chooses
+	var seed *uint32
+	if _, verified := replay.For(gameID); verified {
+		value, err := generateSeed()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate seed: %w", err)
+		}
+		seed = &value
+	}
+
+	// Drop the session's tickets that can no longer be synced so the table stays small
+	s.db.Exec(ctx, `DELETE FROM run_tickets WHERE session_id = $1 AND expires_at < LOCALTIMESTAMP - $2 * INTERVAL '1 second'`,
+		sessionID, int(offlineSyncWindow.Seconds()))
+
+	query := `
+		INSERT INTO run_tickets (session_id, game_id, signing_key, seed, expires_at)
+		VALUES ($1, $2, $3, $4, LOCALTIMESTAMP + $5 * INTERVAL '1 second')
+		RETURNING id, issued_at, expires_at
+	`
+
+	ticket := &models.RunTicketResponse{
+		GameID:     gameID,
+		SigningKey: signingKey,
+		Seed:       seed,
+	}
+	var storedSeed *int64
+	if seed != nil {
+		value := int64(*seed)
+		storedSeed = &value
+	}
+	err = s.db.QueryRow(ctx, query, sessionID, gameID, signingKey, storedSeed, int(runTicketTTL.Seconds())).
+		Scan(&ticket.RunTicket, &ticket.IssuedAt, &ticket.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue run ticket: %w", err)
+	}
+
+	return ticket, nil
+}
+
+// redeemRunTicket verifies a submission's run ticket and signature, marks
+// the ticket used and returns how long the run lasted and its seed. A failed check is
+// recorded for review within tx and returned as a *SubmissionRejectedError;
+// the caller should still commit tx. A run the client timed, having been
+// played offline, only has to end before the ticket expires and can be
+// synced up to offlineSyncWindow later.
+func (s *ScoreService) redeemRunTicket(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, game models.Game, req models.ScoreSubmissionRequest, achievedAt *time.Time) (redeemedRun, error) {
+	if req.RunTicket == "" || req.Signature == "" {
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectMissingTicket)
+	}
+
+	ticketID, err := uuid.Parse(req.RunTicket)
+	if err != nil {
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectUnknownTicket)
+	}
+
+	query := `
+		SELECT session_id, game_id, signing_key, seed, issued_at, expires_at, used_at IS NOT NULL, LOCALTIMESTAMP
+		FROM run_tickets
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	var ticketSession uuid.UUID
+	var ticketGame, signingKey string
+	var issuedAt, expiresAt, now time.Time
+	var seed *int64
+	var used bool
+
+	err = tx.QueryRow(ctx, query, ticketID).Scan(&ticketSession, &ticketGame, &signingKey, &seed, &issuedAt, &expiresAt, &used, &now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectUnknownTicket)
+	}
+	if err != nil {
+		return redeemedRun{}, fmt.Errorf("failed to load run ticket: %w", err)
+	}
+
+	// Tickets belonging to another session or game are left untouched
+	if ticketSession != sessionID || ticketGame != req.GameID {
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectTicketMismatch)
+	}
+	if used {
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectTicketUsed)
+	}
+
+	// From here on the ticket is spent whether or not the submission passes
+	_, err = tx.Exec(ctx, `UPDATE run_tickets SET used_at = $2 WHERE id = $1`, ticketID, now)
+	if err != nil {
+		return redeemedRun{}, fmt.Errorf("failed to redeem run ticket: %w", err)
+	}
+
+	switch {
+	case ticketExpired(now, expiresAt, achievedAt):
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectTicketExpired)
+	case !validSignature(signingKey, req.SignaturePayload(), req.Signature):
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectBadSignature)
+	case now.Sub(issuedAt) < game.Rules.MinRunDuration:
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, RejectTooFast)
+	}
+
+	return redeemedRun{IssuedAt: issuedAt, EndedAt: now, Elapsed: now.Sub(issuedAt), Seed: seed}, nil
+}
+
+// ticketExpired reports whether a ticket expiring at expiresAt can no longer
+// be redeemed at now. A run the client timed as ending at achievedAt must
+// have ended before the expiry, and be synced within offlineSyncWindow of it.
+func ticketExpired(now, expiresAt time.Time, achievedAt *time.Time) bool {
+	if achievedAt == nil {
+		return !now.Before(expiresAt)
+	}
+	return !achievedAt.UTC().Before(expiresAt) || !now.Before(expiresAt.Add(offlineSyncWindow))
+}
+
+// timeRun moves a redeemed run's end to the time the client says it ended,
+// as clientTimedRun checks it. A failed check is recorded within tx and
+// returned as a *SubmissionRejectedError.
+func (s *ScoreService) timeRun(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, game models.Game, req models.ScoreSubmissionRequest, run redeemedRun, achievedAt time.Time) (redeemedRun, error) {
+	run, reason := clientTimedRun(run, achievedAt, game.Rules.MinRunDuration)
+	if reason != "" {
+		return redeemedRun{}, s.reject(ctx, tx, sessionID, req, reason)
+	}
+	return run, nil
+}
+
+// clientTimedRun returns run ending at achievedAt, or the reason it is
+// rejected. The run must last at least minRun from its ticket being issued
+// and end no more than maxClockSkew after it was redeemed; an end time
+// slightly ahead of the server's clock is clamped to the redemption time.
+func clientTimedRun(run redeemedRun, achievedAt time.Time, minRun time.Duration) (redeemedRun, string) {
+	// Run ticket times are the database's wall clock in UTC, read back as UTC
+	achievedAt = achievedAt.UTC()
+
+	switch {
+	case achievedAt.Before(run.IssuedAt) || achievedAt.After(run.EndedAt.Add(maxClockSkew)):
+		return redeemedRun{}, RejectBadTimestamp
+	case achievedAt.Sub(run.IssuedAt) < minRun:
+		return redeemedRun{}, RejectTooFast
+	}
+
+	if achievedAt.Before(run.EndedAt) {
+		run.EndedAt = achievedAt
+	}
+	run.Elapsed = run.EndedAt.Sub(run.IssuedAt)
+	return run, ""
+}
+
+// reject records a submission that failed verification and returns the
+// matching *SubmissionRejectedError
+func (s *ScoreService) reject(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, req models.ScoreSubmissionRequest, reason string) error {
+	query := `
+		INSERT INTO rejected_submissions (session_id, game_id, score, run_ticket, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+	`
+
+	_, err := tx.Exec(ctx, query, sessionID, req.GameID, req.Score, req.RunTicket, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record rejected submission: %w", err)
+	}
+
+	return &SubmissionRejectedError{Reason: reason}
+}
+
+// validSignature checks a hex HMAC-SHA256 signature of payload
+func validSignature(signingKey, payload, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(payload))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// generateSeed generates a random replay seed
+func generateSeed() (uint32, error) {
+	bytes := make([]byte, 4)
+	if _, err := rand.Read(bytes); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(bytes), nil
+}
+
+// generateSigningKey generates a random per-ticket signing key
+func generateSigningKey() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
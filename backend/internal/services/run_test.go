@@ -0,0 +1,120 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"retro-games-backend/internal/models"
+)
+
+func TestValidSignature(t *testing.T) {
+	const key = "0123456789abcdef"
+	req := models.ScoreSubmissionRequest{
+		GameID:    "snake",
+		Score:     1200,
+		RunTicket: "5b0b0a7e-8c1e-4a53-9b6e-3f2d1c0b9a87",
+	}
+	const signature = "337e843e5f63db768983214ff0995d7c3ced2cbb88d8ac910c3b2c8b16f5da82"
+
+	tampered := req
+	tampered.Score = 1201
+
+	tests := []struct {
+		name      string
+		key       string
+		payload   string
+		signature string
+		want      bool
+	}{
+		{"valid", key, req.SignaturePayload(), signature, true},
+		{"uppercase hex", key, req.SignaturePayload(), strings.ToUpper(signature), true},
+		{"other score", key, tampered.SignaturePayload(), signature, false},
+		{"other key", "fedcba9876543210", req.SignaturePayload(), signature, false},
+		{"truncated", key, req.SignaturePayload(), signature[:62], false},
+		{"not hex", key, req.SignaturePayload(), "zz" + signature[2:], false},
+		{"empty", key, req.SignaturePayload(), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validSignature(tt.key, tt.payload, tt.signature); got != tt.want {
+				t.Errorf("validSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTicketExpired(t *testing.T) {
+	issued := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := issued.Add(runTicketTTL)
+	at := func(d time.Duration) *time.Time {
+		ended := issued.Add(d)
+		return &ended
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		achievedAt *time.Time
+		want       bool
+	}{
+		{"online, before expiry", expires.Add(-time.Second), nil, false},
+		{"online, at expiry", expires, nil, true},
+		{"online, after expiry", expires.Add(time.Hour), nil, true},
+		{"offline, synced before expiry", issued.Add(time.Hour), at(time.Minute), false},
+		{"offline, synced days later", expires.Add(3 * 24 * time.Hour), at(time.Hour), false},
+		{"offline, synced at the end of the window", expires.Add(offlineSyncWindow), at(time.Hour), true},
+		{"offline, ended at expiry", expires.Add(time.Hour), at(runTicketTTL), true},
+		{"offline, ended after expiry", expires.Add(time.Hour), at(runTicketTTL + time.Minute), true},
+		{"offline, end in another zone", expires.Add(time.Hour), zoned(issued.Add(time.Hour)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ticketExpired(tt.now, expires, tt.achievedAt); got != tt.want {
+				t.Errorf("ticketExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientTimedRun(t *testing.T) {
+	issued := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	redeemed := issued.Add(time.Hour)
+	run := redeemedRun{IssuedAt: issued, EndedAt: redeemed, Elapsed: time.Hour}
+	const minRun = 10 * time.Second
+
+	tests := []struct {
+		name        string
+		achievedAt  time.Time
+		wantReason  string
+		wantElapsed time.Duration
+	}{
+		{"ended before redemption", issued.Add(10 * time.Minute), "", 10 * time.Minute},
+		{"ended at the minimum", issued.Add(minRun), "", minRun},
+		{"too short", issued.Add(minRun - time.Millisecond), RejectTooFast, 0},
+		{"before the ticket", issued.Add(-time.Second), RejectBadTimestamp, 0},
+		{"slightly ahead of the server", redeemed.Add(30 * time.Second), "", time.Hour},
+		{"too far ahead of the server", redeemed.Add(maxClockSkew + time.Second), RejectBadTimestamp, 0},
+		{"reported in another zone", *zoned(issued.Add(20 * time.Minute)), "", 20 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := clientTimedRun(run, tt.achievedAt, minRun)
+			if reason != tt.wantReason {
+				t.Fatalf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if reason == "" && got.Elapsed != tt.wantElapsed {
+				t.Errorf("elapsed = %v, want %v", got.Elapsed, tt.wantElapsed)
+			}
+		})
+	}
+}
+
+// zoned returns t as the same instant in a zone other than UTC
+func zoned(t time.Time) *time.Time {
+	local := t.In(time.FixedZone("UTC+9", 9*60*60))
+	return &local
+}
@@ -0,0 +1,503 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxSaveSize caps the uncompressed size of a save's data
+const MaxSaveSize = 64 << 10
+
+// maxSaveSlots caps how many slots a player keeps per game
+const maxSaveSlots = 10
+
+// maxSaveRevisions is how many of a slot's latest versions are kept
+const maxSaveRevisions = 10
+
+// saveSlotPattern restricts slot names to short lowercase identifiers
+var saveSlotPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Save errors
+var (
+	ErrSaveNotFound        = errors.New("save not found")
+	ErrRevisionNotFound    = errors.New("save revision not found")
+	ErrInvalidSaveSlot     = errors.New("invalid save slot name")
+	ErrSaveTooLarge        = errors.New("save data too large")
+	ErrTooManySaveSlots    = errors.New("too many save slots")
+	ErrSaveVersionRequired = errors.New("save exists; expected version required")
+)
+
+// SaveConflictError is returned when a save slot is written or deleted
+// expecting a version other than the one it holds. Current is the slot as
+// it stands, so the client can choose between it and its own version.
+type SaveConflictError struct {
+	Current *models.SaveResponse
+}
+
+func (e *SaveConflictError) Error() string {
+	return fmt.Sprintf("save conflict: slot is at version %d", e.Current.Version)
+}
+
+// SaveService handles cloud saves of in-progress games
+type SaveService struct {
+	db    *pgxpool.Pool
+	games *gameCatalog
+}
+
+// NewSaveService creates a new save service
+func NewSaveService(db *pgxpool.Pool) *SaveService {
+	return &SaveService{
+		db:    db,
+		games: newGameCatalog(db),
+	}
+}
+
+// ListSaves lists a player's save slots for a game, most recent first
+func (s *SaveService) ListSaves(ctx context.Context, playerID uuid.UUID, gameID string) (*models.SaveSlotsResponse, error) {
+	if _, err := s.games.Get(ctx, gameID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT slot, version, schema_version, size, updated_at
+		FROM game_saves
+		WHERE player_id = $1 AND game_id = $2
+		ORDER BY updated_at DESC, slot
+	`
+
+	rows, err := s.db.Query(ctx, query, playerID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saves: %w", err)
+	}
+	defer rows.Close()
+
+	slots := []models.SaveSlot{}
+	for rows.Next() {
+		var slot models.SaveSlot
+		if err := rows.Scan(&slot.Slot, &slot.Version, &slot.SchemaVersion, &slot.Size, &slot.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan save: %w", err)
+		}
+		slots = append(slots, slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list saves: %w", err)
+	}
+
+	return &models.SaveSlotsResponse{GameID: gameID, Slots: slots}, nil
+}
+
+// GetSave loads a save slot
+func (s *SaveService) GetSave(ctx context.Context, playerID uuid.UUID, gameID, slot string) (*models.SaveResponse, error) {
+	if err := s.checkSlot(ctx, gameID, slot); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT version, schema_version, size, updated_at, data
+		FROM game_saves
+		WHERE player_id = $1 AND game_id = $2 AND slot = $3
+	`
+
+	save := &models.SaveResponse{GameID: gameID, SaveSlot: models.SaveSlot{Slot: slot}}
+	err := scanSave(s.db.QueryRow(ctx, query, playerID, gameID, slot), save)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSaveNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get save: %w", err)
+	}
+
+	return save, nil
+}
+
+// PutSave writes a save slot and reports whether it created it. expected is
+// the version the client last read: models.SaveNew creates the slot, failing
+// with ErrSaveVersionRequired if it exists, and models.SaveAnyVersion
+// overwrites it unconditionally. Any other version must match the slot's, or
+// a *SaveConflictError is returned.
+func (s *SaveService) PutSave(ctx context.Context, playerID uuid.UUID, gameID, slot string, expected int, req models.PutSaveRequest) (*models.SaveSlot, bool, error) {
+	if err := s.checkSlot(ctx, gameID, slot); err != nil {
+		return nil, false, err
+	}
+	if len(req.Data) > MaxSaveSize {
+		return nil, false, ErrSaveTooLarge
+	}
+
+	compressed, err := compressSave(req.Data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to compress save: %w", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the slot, if it exists, until the write commits
+	current, err := s.lockSlot(ctx, tx, playerID, gameID, slot)
+	if err != nil {
+		return nil, false, err
+	}
+
+	saved := &models.SaveSlot{Slot: slot, SchemaVersion: req.SchemaVersion, Size: len(req.Data)}
+	switch {
+	case current == 0 && expected > 0:
+		return nil, false, ErrSaveNotFound
+	case current == 0:
+		var slots int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM game_saves WHERE player_id = $1 AND game_id = $2`, playerID, gameID).Scan(&slots); err != nil {
+			return nil, false, fmt.Errorf("failed to count saves: %w", err)
+		}
+		if slots >= maxSaveSlots {
+			return nil, false, ErrTooManySaveSlots
+		}
+
+		// Taking the version locks the slot's counter, so a concurrent write
+		// that creates the slot first is seen here
+		version, err := s.nextVersion(ctx, tx, playerID, gameID, slot)
+		if err != nil {
+			return nil, false, err
+		}
+
+		query := `
+			INSERT INTO game_saves (player_id, game_id, slot, version, schema_version, data, size)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (player_id, game_id, slot) DO NOTHING
+			RETURNING version, updated_at
+		`
+		err = tx.QueryRow(ctx, query, playerID, gameID, slot, version, req.SchemaVersion, compressed, saved.Size).
+			Scan(&saved.Version, &saved.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, ErrSaveVersionRequired
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to write save: %w", err)
+		}
+		if err := s.recordRevision(ctx, tx, playerID, gameID, saved, compressed); err != nil {
+			return nil, false, err
+		}
+	case expected == models.SaveNew:
+		return nil, false, ErrSaveVersionRequired
+	case expected != models.SaveAnyVersion && expected != current:
+		return nil, false, s.conflict(ctx, tx, playerID, gameID, slot, current)
+	default:
+		if err := s.updateSlot(ctx, tx, playerID, gameID, saved, compressed); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to write save: %w", err)
+	}
+
+	return saved, current == 0, nil
+}
+
+// DeleteSave deletes a save slot. expected is the version the client last
+// read, or models.SaveAnyVersion to delete whatever the slot holds.
+func (s *SaveService) DeleteSave(ctx context.Context, playerID uuid.UUID, gameID, slot string, expected int) error {
+	if err := s.checkSlot(ctx, gameID, slot); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.lockSlot(ctx, tx, playerID, gameID, slot)
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		return ErrSaveNotFound
+	}
+	if expected != models.SaveAnyVersion && expected != current {
+		return s.conflict(ctx, tx, playerID, gameID, slot, current)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM game_saves WHERE player_id = $1 AND game_id = $2 AND slot = $3`, playerID, gameID, slot)
+	if err != nil {
+		return fmt.Errorf("failed to delete save: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to delete save: %w", err)
+	}
+	return nil
+}
+
+// ListRevisions lists the kept versions of a save slot, newest first
+func (s *SaveService) ListRevisions(ctx context.Context, playerID uuid.UUID, gameID, slot string) (*models.SaveRevisionsResponse, error) {
+	if err := s.checkSlot(ctx, gameID, slot); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT version, schema_version, size, saved_at
+		FROM game_save_revisions
+		WHERE player_id = $1 AND game_id = $2 AND slot = $3
+		ORDER BY version DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, playerID, gameID, slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list save revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.SaveSlot{}
+	for rows.Next() {
+		revision := models.SaveSlot{Slot: slot}
+		if err := rows.Scan(&revision.Version, &revision.SchemaVersion, &revision.Size, &revision.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan save revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list save revisions: %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, ErrSaveNotFound
+	}
+
+	return &models.SaveRevisionsResponse{
+		GameID:    gameID,
+		Slot:      slot,
+		Current:   revisions[0].Version,
+		Revisions: revisions,
+	}, nil
+}
+
+// GetRevision loads one kept version of a save slot
+func (s *SaveService) GetRevision(ctx context.Context, playerID uuid.UUID, gameID, slot string, version int) (*models.SaveResponse, error) {
+	if err := s.checkSlot(ctx, gameID, slot); err != nil {
+		return nil, err
+	}
+
+	return s.loadRevision(ctx, s.db.QueryRow, playerID, gameID, slot, version)
+}
+
+// RestoreSave makes a kept version of a save slot current again by writing
+// it as a new version, so the versions it replaces stay in the history.
+// expected is checked as for PutSave.
+func (s *SaveService) RestoreSave(ctx context.Context, playerID uuid.UUID, gameID, slot string, version, expected int) (*models.SaveSlot, error) {
+	if err := s.checkSlot(ctx, gameID, slot); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.lockSlot(ctx, tx, playerID, gameID, slot)
+	if err != nil {
+		return nil, err
+	}
+	if current == 0 {
+		return nil, ErrSaveNotFound
+	}
+	if expected != models.SaveAnyVersion && expected != current {
+		return nil, s.conflict(ctx, tx, playerID, gameID, slot, current)
+	}
+
+	revision, err := s.loadRevision(ctx, tx.QueryRow, playerID, gameID, slot, version)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := compressSave(revision.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress save: %w", err)
+	}
+
+	saved := &models.SaveSlot{Slot: slot, SchemaVersion: revision.SchemaVersion, Size: revision.Size}
+	if err := s.updateSlot(ctx, tx, playerID, gameID, saved, compressed); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to restore save: %w", err)
+	}
+
+	return saved, nil
+}
+
+// updateSlot overwrites an existing, locked save slot with a new version
+// and records it as a revision
+func (s *SaveService) updateSlot(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, gameID string, saved *models.SaveSlot, compressed []byte) error {
+	version, err := s.nextVersion(ctx, tx, playerID, gameID, saved.Slot)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE game_saves
+		SET version = $4, schema_version = $5, data = $6, size = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE player_id = $1 AND game_id = $2 AND slot = $3
+		RETURNING version, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query, playerID, gameID, saved.Slot, version, saved.SchemaVersion, compressed, saved.Size).
+		Scan(&saved.Version, &saved.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to write save: %w", err)
+	}
+
+	return s.recordRevision(ctx, tx, playerID, gameID, saved, compressed)
+}
+
+// nextVersion takes the next version of a save slot within tx. Versions are
+// counted in game_save_versions, which keeps counting across the slot being
+// deleted and created again, so a version is never given out twice.
+func (s *SaveService) nextVersion(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, gameID, slot string) (int, error) {
+	query := `
+		INSERT INTO game_save_versions (player_id, game_id, slot, version)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (player_id, game_id, slot) DO UPDATE
+		SET version = game_save_versions.version + 1
+		RETURNING version
+	`
+
+	var version int
+	if err := tx.QueryRow(ctx, query, playerID, gameID, slot).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to take save version: %w", err)
+	}
+	return version, nil
+}
+
+// recordRevision keeps a newly written version of a save slot, dropping
+// versions older than the last maxSaveRevisions
+func (s *SaveService) recordRevision(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, gameID string, saved *models.SaveSlot, compressed []byte) error {
+	query := `
+		INSERT INTO game_save_revisions (player_id, game_id, slot, version, schema_version, data, size, saved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := tx.Exec(ctx, query, playerID, gameID, saved.Slot, saved.Version, saved.SchemaVersion, compressed, saved.Size, saved.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record save revision: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM game_save_revisions WHERE player_id = $1 AND game_id = $2 AND slot = $3 AND version <= $4`,
+		playerID, gameID, saved.Slot, saved.Version-maxSaveRevisions)
+	if err != nil {
+		return fmt.Errorf("failed to prune save revisions: %w", err)
+	}
+	return nil
+}
+
+// conflict builds the error for a write expecting a stale version, carrying
+// the slot's current version
+func (s *SaveService) conflict(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, gameID, slot string, current int) error {
+	save, err := s.loadRevision(ctx, tx.QueryRow, playerID, gameID, slot, current)
+	if err != nil {
+		return err
+	}
+	return &SaveConflictError{Current: save}
+}
+
+// loadRevision loads a kept version of a save slot through queryRow, which
+// may belong to the pool or a transaction
+func (s *SaveService) loadRevision(ctx context.Context, queryRow func(context.Context, string, ...any) pgx.Row, playerID uuid.UUID, gameID, slot string, version int) (*models.SaveResponse, error) {
+	query := `
+		SELECT version, schema_version, size, saved_at, data
+		FROM game_save_revisions
+		WHERE player_id = $1 AND game_id = $2 AND slot = $3 AND version = $4
+	`
+
+	revision := &models.SaveResponse{GameID: gameID, SaveSlot: models.SaveSlot{Slot: slot}}
+	err := scanSave(queryRow(ctx, query, playerID, gameID, slot, version), revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get save revision: %w", err)
+	}
+	return revision, nil
+}
+
+// checkSlot validates a game and slot name
+func (s *SaveService) checkSlot(ctx context.Context, gameID, slot string) error {
+	if _, err := s.games.Get(ctx, gameID); err != nil {
+		return err
+	}
+	if !saveSlotPattern.MatchString(slot) {
+		return ErrInvalidSaveSlot
+	}
+	return nil
+}
+
+// lockSlot locks a save slot within tx and returns its version, or 0 if the
+// slot does not exist
+func (s *SaveService) lockSlot(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, gameID, slot string) (int, error) {
+	query := `
+		SELECT version
+		FROM game_saves
+		WHERE player_id = $1 AND game_id = $2 AND slot = $3
+		FOR UPDATE
+	`
+
+	var version int
+	err := tx.QueryRow(ctx, query, playerID, gameID, slot).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load save: %w", err)
+	}
+	return version, nil
+}
+
+// scanSave reads a save's version, schema version, size, time and compressed
+// data from row, in that order, and decompresses the data
+func scanSave(row pgx.Row, save *models.SaveResponse) error {
+	var compressed []byte
+	if err := row.Scan(&save.Version, &save.SchemaVersion, &save.Size, &save.UpdatedAt, &compressed); err != nil {
+		return err
+	}
+
+	data, err := decompressSave(compressed)
+	if err != nil {
+		return fmt.Errorf("failed to decompress save: %w", err)
+	}
+	save.Data = data
+	return nil
+}
+
+// compressSave gzips save data for storage
+func compressSave(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressSave restores stored save data
+func decompressSave(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -2,75 +2,314 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"retro-games-backend/internal/models"
+	"retro-games-backend/internal/replay"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrInvalidMetadata is returned when a score's metadata breaks its game's rules
+var ErrInvalidMetadata = errors.New("invalid score metadata")
+
 // ScoreService handles score operations
 type ScoreService struct {
-	db    *pgxpool.Pool
-	redis *redis.Client
+	db           *pgxpool.Pool
+	redis        *redis.Client
+	rankings     *rankingIndex
+	periods      periodClock
+	games        *gameCatalog
+	achievements *AchievementService
 }
 
-// NewScoreService creates a new score service
-func NewScoreService(db *pgxpool.Pool, redis *redis.Client) *ScoreService {
+// NewScoreService creates a new score service. Period boundaries are
+// computed in loc, or UTC if loc is nil. Accepted scores unlock
+// achievements through achievementService.
+func NewScoreService(db *pgxpool.Pool, redis *redis.Client, loc *time.Location, achievementService *AchievementService) *ScoreService {
 	return &ScoreService{
-		db:    db,
-		redis: redis,
+		db:           db,
+		redis:        redis,
+		rankings:     newRankingIndex(db, redis),
+		periods:      newPeriodClock(loc),
+		games:        newGameCatalog(db),
+		achievements: achievementService,
 	}
 }
 
-// SubmitScore submits a new score for a game
-func (s *ScoreService) SubmitScore(ctx context.Context, sessionID uuid.UUID, gameID string, score int) (*models.ScoreResponse, error) {
-	// Insert new score
-	query := `
-		INSERT INTO scores (session_id, game_id, score)
-		VALUES ($1, $2, $3)
-		RETURNING id, achieved_at
-	`
+// errDuplicateSubmission is returned by recordScore when the submission's
+// idempotency key was already used by an earlier one
+var errDuplicateSubmission = errors.New("duplicate submission")
+
+// recordedScore is a verified submission written within a transaction. XP
+// is set for accepted scores.
+type recordedScore struct {
+	ID         uuid.UUID
+	Status     string
+	FlagReason string
+	AchievedAt time.Time
+	Metadata   models.ScoreMetadata
+	XP         *models.XPGain
+}
 
-	var scoreID uuid.UUID
-	var achievedAt time.Time
+// SubmitScore verifies and records a new score for a game. Submissions
+// without a valid run ticket and signature are rejected with a
+// *SubmissionRejectedError, as are puzzle runs whose replay does not
+// reproduce the score. Scores that break the game's plausibility rules are
+// stored as flagged and left off the leaderboards. A submission repeating
+// an earlier one's ClientRunID gets back the original response instead of
+// being scored again.
+func (s *ScoreService) SubmitScore(ctx context.Context, identity models.Identity, req models.ScoreSubmissionRequest) (*models.ScoreResponse, error) {
+	gameID, score := req.GameID, req.Score
 
-	err := s.db.QueryRow(ctx, query, sessionID, gameID, score).Scan(&scoreID, &achievedAt)
+	game, err := s.games.Get(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	recorded, err := s.recordScore(ctx, tx, identity, game, req, nil)
+	if errors.Is(err, errDuplicateSubmission) {
+		tx.Rollback(ctx)
+		return s.previousSubmission(ctx, identity, req)
+	}
 	if err != nil {
+		return nil, s.commitRejection(ctx, tx, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to submit score: %w", err)
 	}
 
-	// Get personal best
-	personalBest, err := s.GetPersonalBest(ctx, sessionID, gameID)
+	if recorded.Status == models.ScoreFlagged {
+		personalBest, err := s.GetPersonalBest(ctx, identity.PlayerID, gameID)
+		if err != nil {
+			personalBest = 0
+		}
+		response := &models.ScoreResponse{
+			GameID:       gameID,
+			Score:        score,
+			Status:       recorded.Status,
+			FlagReason:   recorded.FlagReason,
+			PersonalBest: personalBest,
+			AchievedAt:   recorded.AchievedAt,
+		}
+		if req.ClientRunID != "" {
+			s.storeSubmissionResponse(ctx, identity.SessionID, req.ClientRunID, response)
+		}
+		return response, nil
+	}
+
+	// Get personal best, dropping the cached value this score may have beaten
+	s.redis.Del(ctx, personalBestKey(identity.PlayerID, gameID))
+	personalBest, err := s.GetPersonalBest(ctx, identity.PlayerID, gameID)
 	if err != nil {
 		personalBest = score // If error, assume this is the first score
 	}
 
-	// Get rank (position in leaderboard)
-	rank, err := s.getScoreRank(ctx, gameID, score)
+	// Add to the game's rankings and read back the player's all-time position
+	rank, err := s.addToRankings(ctx, game, scoreVariants(recorded.Metadata), rankedScore{
+		ScoreID:    recorded.ID,
+		PlayerID:   identity.PlayerID,
+		Score:      score,
+		AchievedAt: recorded.AchievedAt,
+	})
 	if err != nil {
-		rank = 0 // If error, don't show rank
+		// Fall back to counting in Postgres if Redis is unavailable
+		rank, err = s.getScoreRank(ctx, game, personalBest)
+		if err != nil {
+			rank = 0 // If error, don't show rank
+		}
 	}
+	recordBestRank(ctx, s.db, identity.PlayerID, gameID, rank)
 
 	// Invalidate cache for this game
 	s.invalidateGameCache(ctx, gameID)
 
-	return &models.ScoreResponse{
+	// Unlock the achievements the score earned; they can be backfilled if this fails
+	achievements, _ := s.achievements.UnlockEarned(ctx, identity.PlayerID, recorded.ID)
+
+	response := &models.ScoreResponse{
 		GameID:       gameID,
 		Score:        score,
+		Status:       recorded.Status,
 		PersonalBest: personalBest,
 		Rank:         rank,
-		AchievedAt:   achievedAt,
-	}, nil
+		AchievedAt:   recorded.AchievedAt,
+		XP:           recorded.XP,
+		Achievements: achievements,
+	}
+	if req.ClientRunID != "" {
+		s.storeSubmissionResponse(ctx, identity.SessionID, req.ClientRunID, response)
+	}
+	return response, nil
+}
+
+// recordScore verifies a submission and writes it within tx, leaving ranking
+// to the caller once tx commits. achievedAt is when the run ended by the
+// client's clock, or nil to time the score on insert. A rejected submission
+// is recorded within tx and returned as a *SubmissionRejectedError; the
+// caller should still commit tx.
+func (s *ScoreService) recordScore(ctx context.Context, tx pgx.Tx, identity models.Identity, game models.Game, req models.ScoreSubmissionRequest, achievedAt *time.Time) (*recordedScore, error) {
+	// A replay plays the difficulty the score was submitted under, and the
+	// score takes the replay's difficulty when it names none itself
+	if req.Replay != nil {
+		if req.Replay.Difficulty == "" {
+			input := *req.Replay
+			input.Difficulty = req.Difficulty
+			req.Replay = &input
+		} else if req.Difficulty == "" {
+			req.Difficulty = req.Replay.Difficulty
+		}
+	}
+
+	if err := game.Metadata.Validate(req.ScoreMetadata); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	// Hold the idempotency key so a retry of this submission is not scored twice
+	if req.ClientRunID != "" {
+		claimed, err := s.claimSubmissionKey(ctx, tx, identity.SessionID, req.ClientRunID)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, errDuplicateSubmission
+		}
+	}
+
+	recorded, err := s.verifyAndInsert(ctx, tx, identity, game, req, achievedAt)
+	if err != nil {
+		// Free the key of a rejected submission so a corrected retry can use it
+		var rejected *SubmissionRejectedError
+		if errors.As(err, &rejected) && req.ClientRunID != "" {
+			if releaseErr := s.releaseSubmissionKey(ctx, tx, identity.SessionID, req.ClientRunID); releaseErr != nil {
+				return nil, releaseErr
+			}
+		}
+		return nil, err
+	}
+
+	if req.ClientRunID != "" {
+		if err := s.attachSubmissionKey(ctx, tx, identity.SessionID, req.ClientRunID, recorded.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return recorded, nil
+}
+
+// verifyAndInsert redeems a submission's run ticket, replays puzzle runs,
+// checks plausibility and inserts the score within tx
+func (s *ScoreService) verifyAndInsert(ctx context.Context, tx pgx.Tx, identity models.Identity, game models.Game, req models.ScoreSubmissionRequest, achievedAt *time.Time) (*recordedScore, error) {
+	// Redeem the run ticket, keeping the record of a rejected submission
+	run, err := s.redeemRunTicket(ctx, tx, identity.SessionID, game, req, achievedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// A client-timed run must end after its ticket was issued and before now
+	if achievedAt != nil {
+		run, err = s.timeRun(ctx, tx, identity.SessionID, game, req, run, *achievedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Replay puzzle runs to prove the score
+	engine, verified := replay.For(game.ID)
+	var replayed replay.Result
+	if verified {
+		replayed, err = s.verifyReplay(ctx, tx, identity.SessionID, req, run, engine)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Quarantine scores the game could not plausibly produce
+	recorded := &recordedScore{Status: models.ScoreAccepted, Metadata: req.ScoreMetadata}
+	recorded.FlagReason = checkPlausibility(game, req.Score, req.ScoreMetadata, run.Elapsed)
+	if recorded.FlagReason != "" {
+		recorded.Status = models.ScoreFlagged
+	}
+
+	// Insert new score
+	query := `
+		INSERT INTO scores (session_id, player_id, game_id, score, status, flag_reason,
+		                    duration_ms, level, difficulty, game_mode, stats, achieved_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11,
+		        COALESCE($12::timestamp, LOCALTIMESTAMP))
+		RETURNING id, achieved_at
+	`
+
+	var stats interface{}
+	if len(req.Stats) > 0 {
+		stats = req.Stats
+	}
+	var endedAt *time.Time
+	if achievedAt != nil {
+		endedAt = &run.EndedAt
+	}
+
+	err = tx.QueryRow(ctx, query, identity.SessionID, identity.PlayerID, game.ID, req.Score, recorded.Status, recorded.FlagReason,
+		req.DurationMs, req.Level, req.Difficulty, req.Mode, stats, endedAt).Scan(&recorded.ID, &recorded.AchievedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit score: %w", err)
+	}
+
+	if verified {
+		if err := s.storeReplay(ctx, tx, recorded.ID, *run.Seed, req.Replay, replayed); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the player's stats in step, timing runs without a reported duration by their ticket
+	if recorded.Status == models.ScoreAccepted {
+		duration := run.Elapsed
+		if req.DurationMs != nil {
+			duration = time.Duration(*req.DurationMs) * time.Millisecond
+		}
+		err := recordPlayStats(ctx, tx, identity.PlayerID, game, req.Score, duration, recorded.AchievedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		recorded.XP, err = creditXP(ctx, tx, identity.PlayerID, game, recorded.ID, req.Score)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recorded, nil
 }
 
-// GetPersonalBest gets the highest score for a session and game
-func (s *ScoreService) GetPersonalBest(ctx context.Context, sessionID uuid.UUID, gameID string) (int, error) {
+// commitRejection commits the record of a rejected submission, returning
+// err unchanged; any other error leaves tx to be rolled back
+func (s *ScoreService) commitRejection(ctx context.Context, tx pgx.Tx, err error) error {
+	var rejected *SubmissionRejectedError
+	if errors.As(err, &rejected) {
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			return fmt.Errorf("failed to record rejected submission: %w", commitErr)
+		}
+	}
+	return err
+}
+
+// GetPersonalBest gets the best score for a player and game, honouring the
+// game's score direction
+func (s *ScoreService) GetPersonalBest(ctx context.Context, playerID uuid.UUID, gameID string) (int, error) {
 	// Try cache first
-	cacheKey := fmt.Sprintf("personal_best:%s:%s", sessionID.String(), gameID)
+	cacheKey := personalBestKey(playerID, gameID)
 	cached, err := s.redis.Get(ctx, cacheKey).Result()
 	if err == nil {
 		var score int
@@ -79,15 +318,20 @@ func (s *ScoreService) GetPersonalBest(ctx context.Context, sessionID uuid.UUID,
 		}
 	}
 
+	game, err := s.games.Get(ctx, gameID)
+	if err != nil {
+		return 0, err
+	}
+
 	// Fallback to database
 	query := `
-		SELECT COALESCE(MAX(score), 0) 
+		SELECT COALESCE(MAX(score * $3) * $3, 0)
 		FROM scores 
-		WHERE session_id = $1 AND game_id = $2
+		WHERE player_id = $1 AND game_id = $2 AND status = 'accepted'
 	`
 
 	var personalBest int
-	err = s.db.QueryRow(ctx, query, sessionID, gameID).Scan(&personalBest)
+	err = s.db.QueryRow(ctx, query, playerID, gameID, game.Scoring.Direction.Sign()).Scan(&personalBest)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get personal best: %w", err)
 	}
@@ -98,48 +342,44 @@ func (s *ScoreService) GetPersonalBest(ctx context.Context, sessionID uuid.UUID,
 	return personalBest, nil
 }
 
-// GetUserScores gets all scores for a session and game
-func (s *ScoreService) GetUserScores(ctx context.Context, sessionID uuid.UUID, gameID string) (*models.UserScoresResponse, error) {
-	query := `
-		SELECT id, session_id, game_id, score, achieved_at
-		FROM scores 
-		WHERE session_id = $1 AND game_id = $2
-		ORDER BY score DESC, achieved_at DESC
-		LIMIT 10
-	`
-
-	rows, err := s.db.Query(ctx, query, sessionID, gameID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user scores: %w", err)
-	}
-	defer rows.Close()
-
-	var scores []models.Score
-	for rows.Next() {
-		var score models.Score
-		err := rows.Scan(&score.ID, &score.SessionID, &score.GameID, &score.Score, &score.AchievedAt)
+// addToRankings records a score on every board of its variants it counts
+// towards and returns the player's rank on the unfiltered all-time
+// best-per-player board
+func (s *ScoreService) addToRankings(ctx context.Context, game models.Game, variants []models.ScoreVariant, score rankedScore) (int, error) {
+	var playerRank int
+	now := time.Now()
+	for _, b := range s.periods.boardsFor(game, variants, score.AchievedAt) {
+		// Late scores played offline skip boards no longer kept
+		if b.retired(now) {
+			continue
+		}
+		rank, err := s.rankings.Add(ctx, b, score)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan score: %w", err)
+			return 0, err
+		}
+		if b.Variant == (models.ScoreVariant{}) && b.Mode == models.ModeBestPerPlayer && b.Period == models.PeriodAllTime {
+			playerRank = rank
 		}
-		scores = append(scores, score)
 	}
-
-	return &models.UserScoresResponse{
-		Scores: scores,
-		Total:  len(scores),
-	}, nil
+	return playerRank, nil
 }
 
-// getScoreRank calculates the rank of a score in the global leaderboard
-func (s *ScoreService) getScoreRank(ctx context.Context, gameID string, score int) (int, error) {
+// getScoreRank calculates the rank of a player's best score among every
+// player's best score for a game
+func (s *ScoreService) getScoreRank(ctx context.Context, game models.Game, score int) (int, error) {
 	query := `
-		SELECT COUNT(*) + 1 
-		FROM scores 
-		WHERE game_id = $1 AND score > $2
+		SELECT COUNT(*) + 1
+		FROM (
+		    SELECT MAX(score * $3) AS best
+		    FROM scores
+		    WHERE game_id = $1 AND status = 'accepted'
+		    GROUP BY player_id
+		) players
+		WHERE best > $2::int * $3
 	`
 
 	var rank int
-	err := s.db.QueryRow(ctx, query, gameID, score).Scan(&rank)
+	err := s.db.QueryRow(ctx, query, game.ID, score, game.Scoring.Direction.Sign()).Scan(&rank)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate rank: %w", err)
 	}
@@ -147,14 +387,18 @@ func (s *ScoreService) getScoreRank(ctx context.Context, gameID string, score in
 	return rank, nil
 }
 
-// invalidateGameCache invalidates all cache entries for a game
+// invalidateGameCache invalidates the cached rankings a game's scores count
+// towards. The ranking of every game is only cached briefly, so it is left to
+// expire.
 func (s *ScoreService) invalidateGameCache(ctx context.Context, gameID string) {
-	cacheKeys := []string{
-		fmt.Sprintf("leaderboard:%s", gameID),
-		"leaderboard:global",
+	game, err := s.games.Get(ctx, gameID)
+	if err != nil {
+		return
 	}
+	s.redis.Incr(ctx, categoryLeaderboardVersionKey(game.Category))
+}
 
-	for _, key := range cacheKeys {
-		s.redis.Del(ctx, key)
-	}
-}
\ No newline at end of file
+// personalBestKey returns the cache key for a player's personal best in a game
+func personalBestKey(playerID uuid.UUID, gameID string) string {
+	return fmt.Sprintf("personal_best:%s:%s", playerID.String(), gameID)
+}
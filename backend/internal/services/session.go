@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"retro-games-backend/internal/models"
@@ -14,6 +15,13 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// sessionTTL is how long a session lasts after it was last active
+const sessionTTL = 24 * time.Hour
+
+// sessionCacheTTL is how long a validated session is cached in Redis. Each
+// cache miss slides the session's expiry forward.
+const sessionCacheTTL = time.Hour
+
 // SessionService handles session operations
 type SessionService struct {
 	db    *pgxpool.Pool
@@ -28,8 +36,14 @@ func NewSessionService(db *pgxpool.Pool, redis *redis.Client) *SessionService {
 	}
 }
 
-// CreateSession creates a new anonymous session
+// CreateSession creates a new anonymous session for a new anonymous player
 func (s *SessionService) CreateSession(ctx context.Context, ipAddress, userAgent string) (*models.SessionResponse, error) {
+	return s.startSession(ctx, nil, ipAddress, userAgent)
+}
+
+// startSession opens a session for a player, creating an anonymous player
+// first if playerID is nil
+func (s *SessionService) startSession(ctx context.Context, playerID *uuid.UUID, ipAddress, userAgent string) (*models.SessionResponse, error) {
 	// Generate secure session token
 	token, err := generateSessionToken()
 	if err != nil {
@@ -38,24 +52,26 @@ func (s *SessionService) CreateSession(ctx context.Context, ipAddress, userAgent
 
 	// Insert session into database
 	query := `
-		INSERT INTO sessions (session_token, ip_address, user_agent)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		WITH player AS (
+		    INSERT INTO players (created_at)
+		    SELECT CURRENT_TIMESTAMP WHERE $4::uuid IS NULL
+		    RETURNING id
+		)
+		INSERT INTO sessions (session_token, ip_address, user_agent, player_id)
+		SELECT $1, $2, $3, COALESCE($4::uuid, (SELECT id FROM player))
+		RETURNING id, player_id, last_active
 	`
 
-	var sessionID uuid.UUID
-	var createdAt time.Time
+	var identity models.Identity
+	var lastActive time.Time
 
-	err = s.db.QueryRow(ctx, query, token, ipAddress, userAgent).Scan(&sessionID, &createdAt)
+	err = s.db.QueryRow(ctx, query, token, ipAddress, userAgent, playerID).Scan(&identity.SessionID, &identity.PlayerID, &lastActive)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	// Cache session in Redis (1 hour TTL)
-	cacheKey := fmt.Sprintf("session:%s", token)
-	sessionData := fmt.Sprintf("%s:%s", sessionID.String(), createdAt.Format(time.RFC3339))
-	
-	err = s.redis.Set(ctx, cacheKey, sessionData, time.Hour).Err()
+	// Cache session in Redis
+	err = s.redis.Set(ctx, sessionKey(token), cachedIdentity(identity), sessionCacheTTL).Err()
 	if err != nil {
 		// Log error but don't fail the request
 		fmt.Printf("Failed to cache session: %v\n", err)
@@ -63,55 +79,134 @@ func (s *SessionService) CreateSession(ctx context.Context, ipAddress, userAgent
 
 	return &models.SessionResponse{
 		SessionToken: token,
-		ExpiresAt:    createdAt.Add(24 * time.Hour), // 24 hour expiry
+		ExpiresAt:    lastActive.Add(sessionTTL),
 	}, nil
 }
 
-// ValidateSession validates a session token and returns session ID
-func (s *SessionService) ValidateSession(ctx context.Context, token string) (uuid.UUID, error) {
+// ValidateSession validates a session token and returns the session and
+// player it belongs to. Sessions expire sessionTTL after they were last
+// active, and every validation that misses the cache marks the session
+// active again.
+func (s *SessionService) ValidateSession(ctx context.Context, token string) (models.Identity, error) {
 	// Try Redis cache first
-	cacheKey := fmt.Sprintf("session:%s", token)
+	cacheKey := sessionKey(token)
 	cached, err := s.redis.Get(ctx, cacheKey).Result()
-	
 	if err == nil {
-		// Parse cached session data
-		var sessionID uuid.UUID
-		var createdAt time.Time
-		
-		_, parseErr := fmt.Sscanf(cached, "%s:%s", &sessionID, &createdAt)
-		if parseErr == nil {
-			return sessionID, nil
+		if identity, ok := parseCachedIdentity(cached); ok {
+			return identity, nil
 		}
 	}
 
-	// Fallback to database
+	// Fallback to database, sliding the session's expiry
 	query := `
-		SELECT id, created_at 
-		FROM sessions 
-		WHERE session_token = $1 
-		AND created_at > $2
+		UPDATE sessions
+		SET last_active = LOCALTIMESTAMP
+		WHERE session_token = $1
+		  AND revoked_at IS NULL
+		  AND last_active > LOCALTIMESTAMP - $2 * INTERVAL '1 second'
+		RETURNING id, player_id
 	`
 
-	var sessionID uuid.UUID
-	var createdAt time.Time
-	
-	// Sessions expire after 24 hours
-	expiryTime := time.Now().Add(-24 * time.Hour)
-	
-	err = s.db.QueryRow(ctx, query, token, expiryTime).Scan(&sessionID, &createdAt)
+	var identity models.Identity
+	err = s.db.QueryRow(ctx, query, token, int(sessionTTL.Seconds())).Scan(&identity.SessionID, &identity.PlayerID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid or expired session: %w", err)
+		return models.Identity{}, fmt.Errorf("invalid or expired session: %w", err)
 	}
 
 	// Update cache
-	sessionData := fmt.Sprintf("%s:%s", sessionID.String(), createdAt.Format(time.RFC3339))
-	s.redis.Set(ctx, cacheKey, sessionData, time.Hour)
+	s.redis.Set(ctx, cacheKey, cachedIdentity(identity), sessionCacheTTL)
+
+	return identity, nil
+}
 
-	// Update last_active timestamp
-	updateQuery := `UPDATE sessions SET last_active = CURRENT_TIMESTAMP WHERE id = $1`
-	s.db.Exec(ctx, updateQuery, sessionID)
+// GetSession describes a session
+func (s *SessionService) GetSession(ctx context.Context, sessionID uuid.UUID) (*models.SessionInfo, error) {
+	query := `
+		SELECT player_id, created_at, last_active
+		FROM sessions
+		WHERE id = $1
+	`
+
+	info := &models.SessionInfo{SessionID: sessionID}
+	err := s.db.QueryRow(ctx, query, sessionID).Scan(&info.PlayerID, &info.CreatedAt, &info.LastActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	info.ExpiresAt = info.LastActive.Add(sessionTTL)
 
-	return sessionID, nil
+	return info, nil
+}
+
+// RefreshSession replaces a session's token with a new one and restarts its
+// expiry. The old token stops working immediately.
+func (s *SessionService) RefreshSession(ctx context.Context, identity models.Identity, oldToken string) (*models.SessionResponse, error) {
+	token, err := generateSessionToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	query := `
+		UPDATE sessions
+		SET session_token = $3, last_active = LOCALTIMESTAMP
+		WHERE id = $1 AND session_token = $2 AND revoked_at IS NULL
+		RETURNING last_active
+	`
+
+	var lastActive time.Time
+	err = s.db.QueryRow(ctx, query, identity.SessionID, oldToken, token).Scan(&lastActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	s.redis.Del(ctx, sessionKey(oldToken))
+	s.redis.Set(ctx, sessionKey(token), cachedIdentity(identity), sessionCacheTTL)
+
+	return &models.SessionResponse{
+		SessionToken: token,
+		ExpiresAt:    lastActive.Add(sessionTTL),
+	}, nil
+}
+
+// RevokeSession ends a session so its token no longer validates. Its scores
+// are kept.
+func (s *SessionService) RevokeSession(ctx context.Context, sessionID uuid.UUID, token string) error {
+	_, err := s.db.Exec(ctx, `UPDATE sessions SET revoked_at = LOCALTIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if err := s.redis.Del(ctx, sessionKey(token)).Err(); err != nil {
+		return fmt.Errorf("failed to clear cached session: %w", err)
+	}
+	return nil
+}
+
+// sessionKey returns the cache key for a session token
+func sessionKey(token string) string {
+	return fmt.Sprintf("session:%s", token)
+}
+
+// cachedIdentity encodes a session's identity as its cached value
+func cachedIdentity(identity models.Identity) string {
+	return identity.SessionID.String() + ":" + identity.PlayerID.String()
+}
+
+// parseCachedIdentity decodes a cached session value
+func parseCachedIdentity(cached string) (models.Identity, bool) {
+	sessionID, playerID, found := strings.Cut(cached, ":")
+	if !found {
+		return models.Identity{}, false
+	}
+
+	var identity models.Identity
+	var err error
+	if identity.SessionID, err = uuid.Parse(sessionID); err != nil {
+		return models.Identity{}, false
+	}
+	if identity.PlayerID, err = uuid.Parse(playerID); err != nil {
+		return models.Identity{}, false
+	}
+	return identity, true
 }
 
 // generateSessionToken generates a cryptographically secure session token
@@ -121,4 +216,4 @@ func generateSessionToken() (string, error) {
 		return "", err
 	}
 	return hex.EncodeToString(bytes), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxRecentScores is how many of a player's latest scores per game are kept
+// for their trend
+const maxRecentScores = 10
+
+// A trend needs minTrendScores recent scores, and a change of more than
+// steadyTrendPercent either way to count as improving or declining
+const (
+	minTrendScores     = 4
+	steadyTrendPercent = 5.0
+)
+
+// StatsService builds player overviews from the player_stats and
+// player_game_stats summaries that score inserts maintain
+type StatsService struct {
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	rankings *rankingIndex
+	periods  periodClock
+	games    *gameCatalog
+}
+
+// NewStatsService creates a new stats service. Period boundaries are
+// computed in loc, or UTC if loc is nil.
+func NewStatsService(db *pgxpool.Pool, redis *redis.Client, loc *time.Location) *StatsService {
+	return &StatsService{
+		db:       db,
+		redis:    redis,
+		rankings: newRankingIndex(db, redis),
+		periods:  newPeriodClock(loc),
+		games:    newGameCatalog(db),
+	}
+}
+
+// GetPlayerStats returns an overview of a player's accepted scores across
+// every game
+func (s *StatsService) GetPlayerStats(ctx context.Context, playerID uuid.UUID) (*models.PlayerStatsResponse, error) {
+	stats := &models.PlayerStatsResponse{Games: []models.PlayerGameStats{}}
+
+	var totalXP int64
+	err := s.db.QueryRow(ctx, `SELECT total_xp FROM player_xp WHERE player_id = $1`, playerID).Scan(&totalXP)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get player XP: %w", err)
+	}
+	stats.XP = progression(totalXP)
+
+	query := `
+		SELECT CASE WHEN last_played_on >= LOCALTIMESTAMP::date - 1 THEN current_streak ELSE 0 END,
+		       longest_streak, last_played_on
+		FROM player_stats
+		WHERE player_id = $1
+	`
+
+	var lastPlayedOn time.Time
+	err = s.db.QueryRow(ctx, query, playerID).Scan(&stats.CurrentStreak, &stats.LongestStreak, &lastPlayedOn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player stats: %w", err)
+	}
+	stats.LastPlayedOn = lastPlayedOn.Format("2006-01-02")
+
+	query = `
+		SELECT game_id, plays, total_duration_ms, best_score, COALESCE(best_rank, 0), recent_scores, last_played_at
+		FROM player_game_stats
+		WHERE player_id = $1
+		ORDER BY last_played_at DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player game stats: %w", err)
+	}
+	defer rows.Close()
+
+	var recent [][]int32
+	for rows.Next() {
+		var game models.PlayerGameStats
+		var scores []int32
+		err := rows.Scan(&game.GameID, &game.Plays, &game.TotalTimeMs, &game.BestScore, &game.BestRank, &scores, &game.LastPlayedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan player game stats: %w", err)
+		}
+		stats.Games = append(stats.Games, game)
+		recent = append(recent, scores)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get player game stats: %w", err)
+	}
+
+	// Fill in what depends on each game, and the totals
+	categoryPlays := make(map[string]int)
+	for i := range stats.Games {
+		entry := &stats.Games[i]
+		stats.TotalPlays += entry.Plays
+		stats.TotalTimeMs += entry.TotalTimeMs
+
+		game, err := s.games.Get(ctx, entry.GameID)
+		if err != nil {
+			continue // Game removed from the catalog
+		}
+		entry.Category = game.Category
+		categoryPlays[game.Category] += entry.Plays
+		entry.Trend, entry.TrendPercent = scoreTrend(recent[i], game.Scoring.Direction.Sign())
+
+		// Current all-time position, which may beat the best one recorded
+		if rank := s.currentRank(ctx, game, playerID); rank > 0 {
+			entry.Rank = rank
+			if entry.BestRank == 0 || rank < entry.BestRank {
+				entry.BestRank = rank
+			}
+		}
+	}
+	stats.GamesPlayed = len(stats.Games)
+	stats.FavouriteCategory = favouriteCategory(categoryPlays)
+
+	return stats, nil
+}
+
+// currentRank returns a player's position on a game's all-time
+// best-per-player board, or 0 if it cannot be read
+func (s *StatsService) currentRank(ctx context.Context, game models.Game, playerID uuid.UUID) int {
+	b := s.periods.current(game.ID, models.ModeBestPerPlayer, models.PeriodAllTime)
+	b.Direction = game.Scoring.Direction
+
+	member, err := s.rankings.PlayerMember(ctx, b, playerID)
+	if err != nil || member == "" {
+		return 0
+	}
+	rank, err := s.rankings.Rank(ctx, b, member)
+	if err != nil {
+		return 0
+	}
+	return rank
+}
+
+// recordPlayStats adds an accepted score to its player's summaries within
+// the transaction that inserted it. Streaks count calendar days of
+// achievedAt; a score achieved before the player's last day played, as
+// offline scores can be, leaves the streak as it is.
+func recordPlayStats(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, game models.Game, score int, duration time.Duration, achievedAt time.Time) error {
+	query := `
+		INSERT INTO player_game_stats
+		    (player_id, game_id, plays, total_duration_ms, best_score, recent_scores, first_played_at, last_played_at)
+		VALUES ($1, $2, 1, $3, $4, ARRAY[$4::int], $5, $5)
+		ON CONFLICT (player_id, game_id) DO UPDATE
+		SET plays = player_game_stats.plays + 1,
+		    total_duration_ms = player_game_stats.total_duration_ms + EXCLUDED.total_duration_ms,
+		    best_score = CASE WHEN EXCLUDED.best_score * $6 > player_game_stats.best_score * $6
+		                      THEN EXCLUDED.best_score ELSE player_game_stats.best_score END,
+		    recent_scores = (EXCLUDED.recent_scores || player_game_stats.recent_scores)[1:$7],
+		    first_played_at = LEAST(player_game_stats.first_played_at, EXCLUDED.first_played_at),
+		    last_played_at = GREATEST(player_game_stats.last_played_at, EXCLUDED.last_played_at)
+	`
+	_, err := tx.Exec(ctx, query, playerID, game.ID, duration.Milliseconds(), score, achievedAt,
+		game.Scoring.Direction.Sign(), maxRecentScores)
+	if err != nil {
+		return fmt.Errorf("failed to update player game stats: %w", err)
+	}
+
+	query = `
+		INSERT INTO player_stats (player_id, current_streak, longest_streak, last_played_on)
+		VALUES ($1, 1, 1, $2::timestamp::date)
+		ON CONFLICT (player_id) DO UPDATE
+		SET current_streak = CASE
+		        WHEN player_stats.last_played_on >= EXCLUDED.last_played_on THEN player_stats.current_streak
+		        WHEN player_stats.last_played_on = EXCLUDED.last_played_on - 1 THEN player_stats.current_streak + 1
+		        ELSE 1
+		    END,
+		    longest_streak = GREATEST(player_stats.longest_streak, CASE
+		        WHEN player_stats.last_played_on >= EXCLUDED.last_played_on THEN player_stats.current_streak
+		        WHEN player_stats.last_played_on = EXCLUDED.last_played_on - 1 THEN player_stats.current_streak + 1
+		        ELSE 1
+		    END),
+		    last_played_on = GREATEST(player_stats.last_played_on, EXCLUDED.last_played_on)
+	`
+	if _, err := tx.Exec(ctx, query, playerID, achievedAt); err != nil {
+		return fmt.Errorf("failed to update player stats: %w", err)
+	}
+
+	return nil
+}
+
+// recordBestRank keeps the highest all-time position a player has held in
+// a game, once a new score has been ranked
+func recordBestRank(ctx context.Context, db *pgxpool.Pool, playerID uuid.UUID, gameID string, rank int) {
+	if rank <= 0 {
+		return
+	}
+	db.Exec(ctx, `
+		UPDATE player_game_stats
+		SET best_rank = LEAST(COALESCE(best_rank, $3), $3)
+		WHERE player_id = $1 AND game_id = $2
+	`, playerID, gameID, rank)
+}
+
+// scoreTrend compares the newer half of a player's recent scores, newest
+// first, with the older half. sign is the game's score direction, so a
+// falling time counts as improving.
+func scoreTrend(recent []int32, sign int) (string, float64) {
+	if len(recent) < minTrendScores {
+		return "", 0
+	}
+
+	half := len(recent) / 2
+	newer, older := mean(recent[:half]), mean(recent[half:2*half])
+	if older == 0 {
+		return "", 0
+	}
+
+	change := (newer - older) / math.Abs(older) * 100 * float64(sign)
+	change = math.Round(change*10) / 10
+	switch {
+	case change > steadyTrendPercent:
+		return models.TrendImproving, change
+	case change < -steadyTrendPercent:
+		return models.TrendDeclining, change
+	}
+	return models.TrendSteady, change
+}
+
+// mean returns the average of scores
+func mean(scores []int32) float64 {
+	var total float64
+	for _, score := range scores {
+		total += float64(score)
+	}
+	return total / float64(len(scores))
+}
+
+// favouriteCategory returns the category with the most plays, breaking ties
+// alphabetically
+func favouriteCategory(plays map[string]int) string {
+	var favourite string
+	for category, count := range plays {
+		if favourite == "" || count > plays[favourite] || (count == plays[favourite] && category < favourite) {
+			favourite = category
+		}
+	}
+	return favourite
+}
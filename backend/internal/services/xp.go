@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"retro-games-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxLevel is the last level of a prestige
+const maxLevel = 50
+
+// levelThreshold returns the XP within a prestige that reaches level: 100 for
+// level 2, with each level after that taking 100 more than the one before
+func levelThreshold(level int) int64 {
+	l := int64(level)
+	return 50 * l * (l - 1)
+}
+
+// prestigeXP is the XP that completes the last level and earns a prestige
+var prestigeXP = levelThreshold(maxLevel + 1)
+
+// progression returns the prestige and level reached with totalXP
+func progression(totalXP int64) models.Progression {
+	p := models.Progression{TotalXP: totalXP, Prestige: int(totalXP / prestigeXP), Level: 1}
+	xp := totalXP % prestigeXP
+	for p.Level < maxLevel && levelThreshold(p.Level+1) <= xp {
+		p.Level++
+	}
+	p.LevelXP = xp - levelThreshold(p.Level)
+	p.NextLevelXP = levelThreshold(p.Level+1) - levelThreshold(p.Level)
+	return p
+}
+
+// levelUps lists every level reached between two XP totals, in order
+func levelUps(from, to int64) []models.LevelUp {
+	current, target := progression(from), progression(to)
+
+	var ups []models.LevelUp
+	for current.Prestige < target.Prestige || (current.Prestige == target.Prestige && current.Level < target.Level) {
+		if current.Level == maxLevel {
+			current.Prestige, current.Level = current.Prestige+1, 1
+		} else {
+			current.Level++
+		}
+		ups = append(ups, models.LevelUp{Prestige: current.Prestige, Level: current.Level})
+	}
+	return ups
+}
+
+// creditXP credits a player with the XP an accepted score earned within the
+// transaction that inserted it
+func creditXP(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, game models.Game, scoreID uuid.UUID, score int) (*models.XPGain, error) {
+	percentile, err := xpPercentile(ctx, tx, playerID, game, score)
+	if err != nil {
+		return nil, err
+	}
+	earned := game.XP.XP(percentile)
+
+	query := `
+		INSERT INTO xp_ledger (player_id, score_id, game_id, amount, percentile)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.Exec(ctx, query, playerID, scoreID, game.ID, earned, percentile); err != nil {
+		return nil, fmt.Errorf("failed to credit XP: %w", err)
+	}
+
+	totalXP, err := addPlayerXP(ctx, tx, playerID, int64(earned))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.XPGain{
+		Earned:      earned,
+		Percentile:  percentile,
+		Progression: progression(totalXP),
+		LevelUps:    levelUps(totalXP-int64(earned), totalXP),
+	}, nil
+}
+
+// xpPercentile returns the share of the other players of a game whose best a
+// score beats
+func xpPercentile(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, game models.Game, score int) (float64, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE best_score < $3),
+		       COUNT(*) FILTER (WHERE best_score > $3),
+		       COUNT(*)
+		FROM player_game_stats
+		WHERE game_id = $1 AND player_id <> $2
+	`
+
+	var below, above, others int
+	err := tx.QueryRow(ctx, query, game.ID, playerID, score).Scan(&below, &above, &others)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rank score for XP: %w", err)
+	}
+
+	return scorePercentile(game.Scoring.Direction, below, above, others), nil
+}
+
+// scorePercentile returns the share of others a score beats, given how many
+// of their bests are below and above it, counting ties as half. Whether
+// being below or above is beaten follows direction. A game's first player is
+// taken to be average.
+func scorePercentile(direction models.ScoreDirection, below, above, others int) float64 {
+	if others == 0 {
+		return 0.5
+	}
+
+	beaten := below
+	if direction == models.ScoreLowerIsBetter {
+		beaten = above
+	}
+	tied := others - below - above
+
+	percentile := (float64(beaten) + float64(tied)/2) / float64(others)
+	return math.Round(percentile*1000) / 1000
+}
+
+// addPlayerXP adds amount to a player's lifetime XP and returns the new total
+func addPlayerXP(ctx context.Context, tx pgx.Tx, playerID uuid.UUID, amount int64) (int64, error) {
+	query := `
+		INSERT INTO player_xp (player_id, total_xp)
+		VALUES ($1, $2)
+		ON CONFLICT (player_id) DO UPDATE
+		SET total_xp = player_xp.total_xp + EXCLUDED.total_xp,
+		    updated_at = LOCALTIMESTAMP
+		RETURNING total_xp
+	`
+
+	var totalXP int64
+	if err := tx.QueryRow(ctx, query, playerID, amount).Scan(&totalXP); err != nil {
+		return 0, fmt.Errorf("failed to credit XP: %w", err)
+	}
+	return totalXP, nil
+}
+
+// uncreditedScore is an accepted score with no XP credited for it
+type uncreditedScore struct {
+	id     uuid.UUID
+	gameID string
+	score  int
+}
+
+// XPService credits XP for scores accepted before XP was introduced
+type XPService struct {
+	db    *pgxpool.Pool
+	games *gameCatalog
+}
+
+// NewXPService creates a new XP service
+func NewXPService(db *pgxpool.Pool) *XPService {
+	return &XPService{
+		db:    db,
+		games: newGameCatalog(db),
+	}
+}
+
+// Backfill credits XP for every accepted score of an enabled game that has
+// none, and returns how many scores it credited. A score's percentile is
+// taken among the other players' bests now, as their bests when it was
+// played are not kept. Scores already in the ledger are skipped, so running
+// it again, or on several servers at once, credits nothing twice.
+func (s *XPService) Backfill(ctx context.Context) (int, error) {
+	query := `
+		SELECT s.player_id, s.id, s.game_id, s.score
+		FROM scores s
+		JOIN games g ON g.id = s.game_id AND g.enabled = true
+		WHERE s.status = $1 AND s.player_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM xp_ledger x WHERE x.score_id = s.id)
+		ORDER BY s.player_id, s.achieved_at, s.id
+	`
+
+	rows, err := s.db.Query(ctx, query, models.ScoreAccepted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find scores without XP: %w", err)
+	}
+	defer rows.Close()
+
+	// Rows arrive grouped by player; each player is credited in one transaction
+	uncredited := make(map[uuid.UUID][]uncreditedScore)
+	var players []uuid.UUID
+	for rows.Next() {
+		var playerID uuid.UUID
+		var score uncreditedScore
+		if err := rows.Scan(&playerID, &score.id, &score.gameID, &score.score); err != nil {
+			return 0, fmt.Errorf("failed to scan score without XP: %w", err)
+		}
+		if _, ok := uncredited[playerID]; !ok {
+			players = append(players, playerID)
+		}
+		uncredited[playerID] = append(uncredited[playerID], score)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to find scores without XP: %w", err)
+	}
+	rows.Close()
+
+	credited := 0
+	for _, playerID := range players {
+		count, err := s.creditPlayer(ctx, playerID, uncredited[playerID])
+		if err != nil {
+			return credited, err
+		}
+		credited += count
+	}
+
+	return credited, nil
+}
+
+// creditPlayer credits XP for a player's uncredited scores and returns how
+// many it credited
+func (s *XPService) creditPlayer(ctx context.Context, playerID uuid.UUID, scores []uncreditedScore) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var total int64
+	credited := 0
+	for _, score := range scores {
+		game, err := s.games.Get(ctx, score.gameID)
+		if errors.Is(err, ErrUnknownGame) {
+			continue // Disabled since the scores were read
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		percentile, err := xpPercentile(ctx, tx, playerID, game, score.score)
+		if err != nil {
+			return 0, err
+		}
+		earned := game.XP.XP(percentile)
+
+		// Another server may have credited the score since it was read
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO xp_ledger (player_id, score_id, game_id, amount, percentile)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (score_id) DO NOTHING
+		`, playerID, score.id, game.ID, earned, percentile)
+		if err != nil {
+			return 0, fmt.Errorf("failed to credit XP: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		total += int64(earned)
+		credited++
+	}
+
+	if credited > 0 {
+		if _, err := addPlayerXP(ctx, tx, playerID, total); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to credit XP: %w", err)
+	}
+	return credited, nil
+}
+
+// BackfillUncredited backfills the XP of scores that have none, logging how
+// many it credited
+func (s *XPService) BackfillUncredited(ctx context.Context) {
+	count, err := s.Backfill(ctx)
+	if err != nil {
+		log.Printf("Failed to backfill XP: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Backfilled XP for %d scores", count)
+	}
+}
+
+// GetXPLeaderboard ranks players by lifetime XP; players who reached the
+// same total earlier rank first
+func (l *LeaderboardService) GetXPLeaderboard(ctx context.Context, limit int) (*models.XPLeaderboardResponse, error) {
+	query := `
+		SELECT x.player_id::text, COALESCE(pp.display_name, ''), COALESCE(pp.avatar, ''), COALESCE(pp.country, ''),
+		       x.total_xp
+		FROM player_xp x
+		LEFT JOIN player_profiles pp ON pp.player_id = x.player_id
+		ORDER BY x.total_xp DESC, x.updated_at ASC
+		LIMIT $1
+	`
+
+	rows, err := l.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch XP leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.XPLeaderboardEntry{}
+	for rows.Next() {
+		var entry models.XPLeaderboardEntry
+		var playerID string
+		var totalXP int64
+
+		if err := rows.Scan(&playerID, &entry.DisplayName, &entry.Avatar, &entry.Country, &totalXP); err != nil {
+			return nil, fmt.Errorf("failed to scan XP leaderboard entry: %w", err)
+		}
+
+		entry.Rank = len(entries) + 1
+		entry.PlayerID = shortPlayerID(playerID)
+		entry.Progression = progression(totalXP)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch XP leaderboard: %w", err)
+	}
+
+	return &models.XPLeaderboardResponse{
+		Entries: entries,
+		Total:   len(entries),
+	}, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"retro-games-backend/internal/models"
+)
+
+func TestLevelThreshold(t *testing.T) {
+	tests := []struct {
+		level int
+		want  int64
+	}{
+		{1, 0},
+		{2, 100},
+		{3, 300},
+		{10, 4500},
+		{maxLevel, 122500},
+		{maxLevel + 1, 127500},
+	}
+
+	for _, tt := range tests {
+		if got := levelThreshold(tt.level); got != tt.want {
+			t.Errorf("levelThreshold(%d) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+	if prestigeXP != 127500 {
+		t.Errorf("prestigeXP = %d, want 127500", prestigeXP)
+	}
+}
+
+func TestProgression(t *testing.T) {
+	tests := []struct {
+		totalXP int64
+		want    models.Progression
+	}{
+		{0, models.Progression{Level: 1, LevelXP: 0, NextLevelXP: 100}},
+		{99, models.Progression{Level: 1, LevelXP: 99, NextLevelXP: 100}},
+		{100, models.Progression{Level: 2, LevelXP: 0, NextLevelXP: 200}},
+		{450, models.Progression{Level: 3, LevelXP: 150, NextLevelXP: 300}},
+		{127499, models.Progression{Level: maxLevel, LevelXP: 4999, NextLevelXP: 5000}},
+		{127500, models.Progression{Prestige: 1, Level: 1, LevelXP: 0, NextLevelXP: 100}},
+		{255100, models.Progression{Prestige: 2, Level: 2, LevelXP: 0, NextLevelXP: 200}},
+	}
+
+	for _, tt := range tests {
+		tt.want.TotalXP = tt.totalXP
+		if got := progression(tt.totalXP); got != tt.want {
+			t.Errorf("progression(%d) = %+v, want %+v", tt.totalXP, got, tt.want)
+		}
+	}
+}
+
+func TestLevelUps(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int64
+		want     []models.LevelUp
+	}{
+		{"no change", 50, 50, nil},
+		{"within a level", 10, 90, nil},
+		{"one level", 90, 110, []models.LevelUp{{Level: 2}}},
+		{"several levels", 90, 310, []models.LevelUp{{Level: 2}, {Level: 3}}},
+		{"into a prestige", 127400, 127600, []models.LevelUp{{Prestige: 1, Level: 1}, {Prestige: 1, Level: 2}}},
+		{"exactly a prestige", 127499, 127500, []models.LevelUp{{Prestige: 1, Level: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := levelUps(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("levelUps(%d, %d) = %+v, want %+v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorePercentile(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction models.ScoreDirection
+		below     int
+		above     int
+		others    int
+		want      float64
+	}{
+		{"first player", models.ScoreHigherIsBetter, 0, 0, 0, 0.5},
+		{"first player, lower is better", models.ScoreLowerIsBetter, 0, 0, 0, 0.5},
+		{"beats everyone", models.ScoreHigherIsBetter, 4, 0, 4, 1},
+		{"beaten by everyone", models.ScoreHigherIsBetter, 0, 4, 4, 0},
+		{"ties count half", models.ScoreHigherIsBetter, 1, 1, 4, 0.5},
+		{"rounded", models.ScoreHigherIsBetter, 1, 2, 3, 0.333},
+		{"lowest time beats everyone", models.ScoreLowerIsBetter, 0, 4, 4, 1},
+		{"highest time beaten by everyone", models.ScoreLowerIsBetter, 4, 0, 4, 0},
+		{"lower is better, ties count half", models.ScoreLowerIsBetter, 1, 2, 4, 0.625},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scorePercentile(tt.direction, tt.below, tt.above, tt.others); got != tt.want {
+				t.Errorf("scorePercentile(%s, %d, %d, %d) = %v, want %v",
+					tt.direction, tt.below, tt.above, tt.others, got, tt.want)
+			}
+		})
+	}
+}